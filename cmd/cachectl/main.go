@@ -0,0 +1,160 @@
+// Command cachectl imports and exports tenant semantic cache entries as JSONL.
+//
+//	cachectl import -tenant 1 -file faq.jsonl [-dry-run] [-overwrite]
+//	cachectl export -tenant 1 [-file cache.jsonl]
+//
+// It reads the same environment as the gateway (DATABASE_URL, REDIS_URL,
+// EMBEDDING_*, CACHE_*).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/cache"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/config"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/db"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/embedding"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/secrets"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	tenantID := flags.Int("tenant", 0, "tenant ID")
+	file := flags.String("file", "", "JSONL file (default stdin for import, stdout for export)")
+	dryRun := flags.Bool("dry-run", false, "import: validate and count without writing")
+	overwrite := flags.Bool("overwrite", false, "import: replace entries that are already cached")
+	batchSize := flags.Int("batch-size", 100, "import: entries embedded and written per batch")
+	flags.Parse(os.Args[2:])
+
+	if *tenantID <= 0 {
+		log.Fatal("-tenant is required")
+	}
+
+	ctx := context.Background()
+	semanticCache, database := setup()
+	defer database.Close()
+
+	switch os.Args[1] {
+	case "import":
+		var in io.Reader = os.Stdin
+		if *file != "" {
+			f, err := os.Open(*file)
+			if err != nil {
+				log.Fatal("Failed to open import file:", err)
+			}
+			defer f.Close()
+			in = f
+		}
+
+		report, err := semanticCache.Import(ctx, *tenantID, in, cache.ImportOptions{
+			DryRun:    *dryRun,
+			Overwrite: *overwrite,
+			BatchSize: *batchSize,
+		})
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		if err != nil {
+			log.Fatal("Import failed:", err)
+		}
+
+	case "export":
+		var out io.Writer = os.Stdout
+		if *file != "" {
+			f, err := os.Create(*file)
+			if err != nil {
+				log.Fatal("Failed to create export file:", err)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		count, err := semanticCache.Export(ctx, *tenantID, out)
+		if err != nil {
+			log.Fatal("Export failed:", err)
+		}
+		log.Printf("Exported %d entries for tenant %d", count, *tenantID)
+
+	default:
+		usage()
+	}
+}
+
+func setup() (*cache.SemanticCache, *db.DB) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+
+	database, err := db.NewDB(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	embedder, err := embedding.New(embedding.Options{
+		Provider:  cfg.EmbeddingProvider,
+		URL:       cfg.EmbeddingURL,
+		Model:     cfg.EmbeddingModel,
+		APIKey:    cfg.EmbeddingAPIKey,
+		Timeout:   cfg.EmbeddingTimeout,
+		CacheSize: cfg.EmbeddingCacheSize,
+		Dims:      cfg.EmbeddingDims,
+	})
+	if err != nil {
+		log.Fatal("Failed to initialize embedding provider:", err)
+	}
+
+	normalizer, err := cache.NewNormalizer(cfg.CacheNormalizers)
+	if err != nil {
+		log.Fatal("Failed to initialize prompt normalizer:", err)
+	}
+
+	keys, err := secrets.New(secrets.Options{
+		Provider:    cfg.KMSProvider,
+		MasterKey:   cfg.KMSMasterKey,
+		KeyringPath: cfg.KMSKeyringPath,
+	})
+	if err != nil {
+		log.Fatal("Failed to initialize key management:", err)
+	}
+	if cfg.CacheEncryptAtRest && keys == nil {
+		log.Fatal("CACHE_ENCRYPT_AT_REST requires KMS_PROVIDER or KMS_MASTER_KEY")
+	}
+
+	semanticCache, err := cache.NewSemanticCache(database, cfg.RedisURL, embedder, normalizer, cache.Policy{
+		TTL:            cfg.CacheTTL,
+		SoftTTL:        cfg.CacheSoftTTL,
+		MaxEntries:     cfg.CacheMaxEntries,
+		EvictionPolicy: cfg.CacheEvictionPolicy,
+		EncryptAtRest:  cfg.CacheEncryptAtRest,
+
+		SimilarityThreshold: cfg.CacheSimilarityThreshold,
+	}, cache.Storage{
+		Keys:            keys,
+		CompressMinSize: cfg.CacheCompressMinSize,
+	}, cache.Feedback{
+		BlacklistAfter:      cfg.CacheFeedbackBlacklistAfter,
+		RaiseThresholdAfter: cfg.CacheFeedbackRaiseAfter,
+		MaxThreshold:        cfg.CacheMaxSimilarityThreshold,
+	}, nil)
+	if err != nil {
+		log.Fatal("Failed to initialize semantic cache:", err)
+	}
+
+	return semanticCache, database
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: cachectl import|export -tenant ID [-file path] [-dry-run] [-overwrite]")
+	os.Exit(2)
+}
@@ -1,17 +1,27 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/HanTheDev/multi-tenant-api-gateway/internal/admin"
 	"github.com/HanTheDev/multi-tenant-api-gateway/internal/auth"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/balancer"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/breaker"
 	"github.com/HanTheDev/multi-tenant-api-gateway/internal/cache"
 	"github.com/HanTheDev/multi-tenant-api-gateway/internal/config"
 	"github.com/HanTheDev/multi-tenant-api-gateway/internal/db"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/embedding"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/pricing"
 	"github.com/HanTheDev/multi-tenant-api-gateway/internal/proxy"
 	"github.com/HanTheDev/multi-tenant-api-gateway/internal/ratelimit"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/routing"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/secrets"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/vault"
 	"github.com/gorilla/mux"
 )
 
@@ -36,11 +46,65 @@ func main() {
 	}
 	defer limiter.Close()
 
+	// Initialize embedding provider
+	embedder, err := embedding.New(embedding.Options{
+		Provider:  cfg.EmbeddingProvider,
+		URL:       cfg.EmbeddingURL,
+		Model:     cfg.EmbeddingModel,
+		APIKey:    cfg.EmbeddingAPIKey,
+		Timeout:   cfg.EmbeddingTimeout,
+		BatchSize: cfg.EmbeddingBatchSize,
+		BatchWait: cfg.EmbeddingBatchWait,
+		CacheSize: cfg.EmbeddingCacheSize,
+		Dims:      cfg.EmbeddingDims,
+	})
+	if err != nil {
+		log.Fatal("Failed to initialize embedding provider:", err)
+	}
+
 	// Initialize semantic cache
-	semanticCache, err := cache.NewSemanticCache(database, cfg.RedisURL, "http://localhost:5000")
+	normalizer, err := cache.NewNormalizer(cfg.CacheNormalizers)
+	if err != nil {
+		log.Fatal("Failed to initialize prompt normalizer:", err)
+	}
+
+	keys, err := secrets.New(secrets.Options{
+		Provider:    cfg.KMSProvider,
+		MasterKey:   cfg.KMSMasterKey,
+		KeyringPath: cfg.KMSKeyringPath,
+	})
+	if err != nil {
+		log.Fatal("Failed to initialize key management:", err)
+	}
+	if cfg.CacheEncryptAtRest && keys == nil {
+		log.Fatal("CACHE_ENCRYPT_AT_REST requires KMS_PROVIDER or KMS_MASTER_KEY")
+	}
+
+	prices, err := pricing.Parse(cfg.ModelPrices)
+	if err != nil {
+		log.Fatal("Invalid MODEL_PRICES:", err)
+	}
+
+	semanticCache, err := cache.NewSemanticCache(database, cfg.RedisURL, embedder, normalizer, cache.Policy{
+		TTL:            cfg.CacheTTL,
+		SoftTTL:        cfg.CacheSoftTTL,
+		MaxEntries:     cfg.CacheMaxEntries,
+		EvictionPolicy: cfg.CacheEvictionPolicy,
+		EncryptAtRest:  cfg.CacheEncryptAtRest,
+
+		SimilarityThreshold: cfg.CacheSimilarityThreshold,
+	}, cache.Storage{
+		Keys:            keys,
+		CompressMinSize: cfg.CacheCompressMinSize,
+	}, cache.Feedback{
+		BlacklistAfter:      cfg.CacheFeedbackBlacklistAfter,
+		RaiseThresholdAfter: cfg.CacheFeedbackRaiseAfter,
+		MaxThreshold:        cfg.CacheMaxSimilarityThreshold,
+	}, prices)
 	if err != nil {
 		log.Fatal("Failed to initialize semantic cache:", err)
 	}
+	semanticCache.StartJanitor(context.Background(), cfg.CacheJanitorInterval)
 
 	// Initialize router
 	router := mux.NewRouter()
@@ -52,12 +116,82 @@ func main() {
 	router.HandleFunc("/health", healthHandler).Methods("GET")
 	router.HandleFunc("/auth/token", tokenHandler(database, cfg.JWTSecret)).Methods("POST")
 
+	// Backend health, fed by the proxy and reported by the admin API
+	health := balancer.NewHealth(balancer.HealthOptions{
+		ConsecutiveFailures: cfg.OutlierFailures,
+		BaseEjection:        cfg.OutlierBaseEjection,
+		MaxEjection:         cfg.OutlierMaxEjection,
+		ProbeFailures:       cfg.HealthCheckFailures,
+	})
+
+	// Circuit breakers per backend, with every transition kept for the admin API
+	instance, _ := os.Hostname()
+	breakers := breaker.NewSet(breaker.Options{
+		Window:           cfg.CircuitWindow,
+		MinRequests:      cfg.CircuitMinRequests,
+		FailureRate:      cfg.CircuitFailureRate,
+		SlowCallDuration: cfg.CircuitSlowCallDuration,
+		SlowCallRate:     cfg.CircuitSlowCallRate,
+		OpenDuration:     cfg.CircuitOpenDuration,
+		HalfOpenCalls:    cfg.CircuitHalfOpenCalls,
+	}, func(t breaker.Transition) {
+		go func() {
+			err := database.RecordCircuitBreakerEvent(context.Background(), &models.CircuitBreakerEvent{
+				Backend:   t.Backend,
+				FromState: string(t.From),
+				ToState:   string(t.To),
+				Reason:    t.Reason,
+				Instance:  instance,
+				CreatedAt: t.At,
+			})
+			if err != nil {
+				log.Printf("❌ Failed to record circuit breaker event: %v", err)
+			}
+		}()
+	})
+
 	// Admin routes (you may want to add admin auth middleware here)
-	adminHandler := admin.NewAdminHandler(database)
+	// Upstream provider credentials, sealed with the same key management as the cache
+	credentials := vault.New(database, keys, cfg.CredentialCacheTTL)
+	routes := routing.New(database, cfg.RouteCacheTTL)
+
+	adminHandler := admin.NewAdminHandler(database, semanticCache, health, breakers, credentials, routes)
 	adminHandler.RegisterRoutes(router)
 
 	// Protected proxy routes
-	proxyHandler := proxy.NewHandler(database, limiter, semanticCache)
+	proxyHandler := proxy.NewHandler(database, limiter, semanticCache, proxy.Options{
+		Coalesce:          cfg.CoalesceRequests,
+		CoalesceRedisLock: cfg.CoalesceRedisLock,
+		CoalesceLockTTL:   cfg.CoalesceLockTTL,
+		StreamChunkSize:   cfg.StreamChunkSize,
+		StreamPacing:      cfg.StreamPacing,
+		Transport: proxy.TransportOptions{
+			DialTimeout:           cfg.UpstreamDialTimeout,
+			TLSHandshakeTimeout:   cfg.UpstreamTLSHandshakeTimeout,
+			ResponseHeaderTimeout: cfg.UpstreamResponseHeaderTimeout,
+			IdleConnTimeout:       cfg.UpstreamIdleConnTimeout,
+			MaxIdleConnsPerHost:   cfg.UpstreamMaxIdleConnsPerHost,
+			MaxConnsPerHost:       cfg.UpstreamMaxConnsPerHost,
+			HTTP2:                 cfg.UpstreamHTTP2,
+		},
+		Retry: proxy.RetryOptions{
+			MaxRetries:  cfg.RetryMax,
+			BaseDelay:   cfg.RetryBaseDelay,
+			MaxDelay:    cfg.RetryMaxDelay,
+			BudgetRatio: cfg.RetryBudgetRatio,
+			BudgetBurst: cfg.RetryBudgetBurst,
+		},
+		Health:   health,
+		Breakers: breakers,
+		Vault:    credentials,
+		Routes:   routes,
+	})
+	proxyHandler.StartHealthChecks(context.Background(), proxy.HealthCheckOptions{
+		Interval:       cfg.HealthCheckInterval,
+		Timeout:        cfg.HealthCheckTimeout,
+		ExpectedStatus: cfg.HealthCheckExpectedStatus,
+	})
+	router.Handle("/cache/feedback", authMiddleware.Authenticate(http.HandlerFunc(proxyHandler.Feedback))).Methods("POST")
 	router.PathPrefix("/api/").Handler(
 		authMiddleware.Authenticate(proxyHandler),
 	)
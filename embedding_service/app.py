@@ -8,6 +8,12 @@ model = SentenceTransformer('all-MiniLM-L6-v2')
 @app.route('/embed', methods=['POST'])
 def embed():
     data = request.json
+
+    # Batch form: {"texts": [...]} -> {"embeddings": [[...], ...]}
+    if 'texts' in data:
+        embeddings = model.encode(data['texts'])
+        return jsonify({'embeddings': embeddings.tolist()})
+
     text = data.get('text', '')
     
     embedding = model.encode(text)
@@ -2,24 +2,38 @@ package admin
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"regexp"
 	"strconv"
 
 	"crypto/rand"
 	"encoding/hex"
 
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/balancer"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/breaker"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/cache"
 	"github.com/HanTheDev/multi-tenant-api-gateway/internal/db"
 	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/providers"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/routing"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/vault"
 	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
 )
 
 type AdminHandler struct {
-	db *db.DB
+	db       *db.DB
+	cache    *cache.SemanticCache
+	health   *balancer.Health
+	breakers *breaker.Set
+	vault    *vault.Vault
+	routes   *routing.Store
 }
 
-func NewAdminHandler(database *db.DB) *AdminHandler {
-	return &AdminHandler{db: database}
+func NewAdminHandler(database *db.DB, semCache *cache.SemanticCache, health *balancer.Health, breakers *breaker.Set, credentials *vault.Vault, routes *routing.Store) *AdminHandler {
+	return &AdminHandler{db: database, cache: semCache, health: health, breakers: breakers, vault: credentials, routes: routes}
 }
 
 func (h *AdminHandler) RegisterRoutes(router *mux.Router) {
@@ -34,6 +48,25 @@ func (h *AdminHandler) RegisterRoutes(router *mux.Router) {
 	// Analytics
 	router.HandleFunc("/admin/tenants/{id}/analytics", h.GetAnalytics).Methods("GET")
 	router.HandleFunc("/admin/cache/stats", h.GetCacheStats).Methods("GET")
+
+	// Cache settings
+	router.HandleFunc("/admin/tenants/{id}/cache/settings", h.GetCacheSettings).Methods("GET")
+	router.HandleFunc("/admin/tenants/{id}/cache/settings", h.PutCacheSettings).Methods("PUT")
+
+	// Cache entries
+	h.registerCacheRoutes(router)
+
+	// Shared cache namespaces
+	h.registerNamespaceRoutes(router)
+
+	// Backend pools
+	h.registerBackendRoutes(router)
+
+	// Upstream credentials
+	h.registerCredentialRoutes(router)
+
+	// Model allowlists, aliases and routes
+	h.registerModelPolicyRoutes(router)
 }
 
 func (h *AdminHandler) CreateTenant(w http.ResponseWriter, r *http.Request) {
@@ -41,6 +74,7 @@ func (h *AdminHandler) CreateTenant(w http.ResponseWriter, r *http.Request) {
 		Name             string `json:"name"`
 		BackendURL       string `json:"backend_url"`
 		RateLimitPerHour int    `json:"rate_limit_per_hour"`
+		APIFormat        string `json:"api_format"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -57,6 +91,13 @@ func (h *AdminHandler) CreateTenant(w http.ResponseWriter, r *http.Request) {
 	if req.RateLimitPerHour <= 0 {
 		req.RateLimitPerHour = 1000 // Default
 	}
+	if req.APIFormat == "" {
+		req.APIFormat = string(providers.OpenAI)
+	}
+	if !providers.Format(req.APIFormat).Valid() {
+		http.Error(w, apiFormatError, http.StatusBadRequest)
+		return
+	}
 
 	// Generate API key
 	apiKey, err := generateAPIKey()
@@ -70,6 +111,7 @@ func (h *AdminHandler) CreateTenant(w http.ResponseWriter, r *http.Request) {
 		APIKey:           apiKey,
 		BackendURL:       req.BackendURL,
 		RateLimitPerHour: req.RateLimitPerHour,
+		APIFormat:        req.APIFormat,
 	}
 
 	if err := h.db.CreateTenant(r.Context(), tenant); err != nil {
@@ -124,6 +166,9 @@ func (h *AdminHandler) UpdateTenant(w http.ResponseWriter, r *http.Request) {
 		Name             *string `json:"name"`
 		BackendURL       *string `json:"backend_url"`
 		RateLimitPerHour *int    `json:"rate_limit_per_hour"`
+		LBStrategy       *string `json:"lb_strategy"`
+		LBHashHeader     *string `json:"lb_hash_header"`
+		APIFormat        *string `json:"api_format"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
@@ -131,10 +176,58 @@ func (h *AdminHandler) UpdateTenant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.db.UpdateTenant(r.Context(), id, updates); err != nil {
+	if updates.APIFormat != nil && !providers.Format(*updates.APIFormat).Valid() {
+		http.Error(w, apiFormatError, http.StatusBadRequest)
+		return
+	}
+	if updates.LBStrategy != nil || updates.LBHashHeader != nil {
+		tenant, err := h.db.GetTenantByID(r.Context(), id)
+		if err != nil {
+			http.Error(w, "Tenant not found", http.StatusNotFound)
+			return
+		}
+		if updates.LBStrategy != nil {
+			tenant.LBStrategy = *updates.LBStrategy
+		}
+		if updates.LBHashHeader != nil {
+			tenant.LBHashHeader = *updates.LBHashHeader
+		}
+		if !balancer.Strategy(tenant.LBStrategy).Valid() {
+			http.Error(w, "lb_strategy must be round_robin, least_outstanding, latency_ewma or consistent_hash", http.StatusBadRequest)
+			return
+		}
+		if balancer.Strategy(tenant.LBStrategy) == balancer.ConsistentHash && tenant.LBHashHeader == "" {
+			http.Error(w, "consistent_hash requires lb_hash_header", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The proxy picks up a new backend_url on the tenant's next request
+	updateMap := make(map[string]interface{})
+	if updates.Name != nil {
+		updateMap["name"] = *updates.Name
+	}
+	if updates.BackendURL != nil {
+		updateMap["backend_url"] = *updates.BackendURL
+	}
+	if updates.RateLimitPerHour != nil {
+		updateMap["rate_limit_per_hour"] = *updates.RateLimitPerHour
+	}
+	if updates.LBStrategy != nil {
+		updateMap["lb_strategy"] = *updates.LBStrategy
+	}
+	if updates.LBHashHeader != nil {
+		updateMap["lb_hash_header"] = *updates.LBHashHeader
+	}
+	if updates.APIFormat != nil {
+		updateMap["api_format"] = *updates.APIFormat
+	}
+
+	if err := h.db.UpdateTenant(r.Context(), id, updateMap); err != nil {
 		http.Error(w, "Failed to update tenant", http.StatusInternalServerError)
 		return
 	}
+	h.routes.Forget(id)
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
@@ -152,6 +245,7 @@ func (h *AdminHandler) DeleteTenant(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to delete tenant", http.StatusInternalServerError)
 		return
 	}
+	h.routes.Forget(id)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -210,11 +304,112 @@ func (h *AdminHandler) GetCacheStats(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to get cache stats", http.StatusInternalServerError)
 		return
 	}
+	stats["embedder"] = h.cache.EmbedderInfo()
+
+	// Precision estimates, e.g. ?tenant_id=3 for one tenant
+	tenantID := 0
+	if v := r.URL.Query().Get("tenant_id"); v != "" {
+		if tenantID, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+			return
+		}
+	}
+	if stats["feedback"], err = h.db.GetCacheFeedbackStats(r.Context(), tenantID); err != nil {
+		http.Error(w, "Failed to get cache stats", http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(stats)
 }
 
+func (h *AdminHandler) GetCacheSettings(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.db.GetTenantCacheSettings(r.Context(), tenantID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// No overrides yet, every field uses the gateway default
+		settings = &models.TenantCacheSettings{TenantID: tenantID}
+	} else if err != nil {
+		http.Error(w, "Failed to get cache settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
+func (h *AdminHandler) PutCacheSettings(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var settings models.TenantCacheSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	settings.TenantID = tenantID
+
+	// Validate inputs
+	if settings.TTLSeconds != nil && *settings.TTLSeconds < 0 {
+		http.Error(w, "ttl_seconds must not be negative", http.StatusBadRequest)
+		return
+	}
+	if settings.SoftTTLSeconds != nil && *settings.SoftTTLSeconds < 0 {
+		http.Error(w, "soft_ttl_seconds must not be negative", http.StatusBadRequest)
+		return
+	}
+	if settings.SoftTTLSeconds != nil && settings.TTLSeconds != nil && *settings.TTLSeconds > 0 && *settings.SoftTTLSeconds >= *settings.TTLSeconds {
+		http.Error(w, "soft_ttl_seconds must be shorter than ttl_seconds", http.StatusBadRequest)
+		return
+	}
+	if settings.EncryptAtRest != nil && *settings.EncryptAtRest && !h.cache.CanEncrypt() {
+		http.Error(w, "encrypt_at_rest requires KMS_PROVIDER or KMS_MASTER_KEY to be configured", http.StatusBadRequest)
+		return
+	}
+	if settings.SharedCache != nil && *settings.SharedCache && settings.EncryptAtRest != nil && *settings.EncryptAtRest {
+		http.Error(w, "shared_cache cannot be enabled together with encrypt_at_rest", http.StatusBadRequest)
+		return
+	}
+	if settings.SimilarityThreshold != nil && (*settings.SimilarityThreshold <= 0 || *settings.SimilarityThreshold > 1) {
+		http.Error(w, "similarity_threshold must be in (0, 1]", http.StatusBadRequest)
+		return
+	}
+	if settings.MaxEntries != nil && *settings.MaxEntries < 0 {
+		http.Error(w, "max_entries must not be negative", http.StatusBadRequest)
+		return
+	}
+	if settings.EvictionPolicy != nil && *settings.EvictionPolicy != "lru" && *settings.EvictionPolicy != "lfu" {
+		http.Error(w, "eviction_policy must be lru or lfu", http.StatusBadRequest)
+		return
+	}
+	for _, rule := range settings.NormalizationRules {
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			http.Error(w, "Invalid normalization rule pattern: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.db.PutTenantCacheSettings(r.Context(), &settings); err != nil {
+		log.Printf("Failed to update cache settings: %v", err)
+		http.Error(w, "Failed to update cache settings", http.StatusInternalServerError)
+		return
+	}
+	h.cache.ForgetSettings(tenantID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
+
 func generateAPIKey() (string, error) {
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
@@ -0,0 +1,315 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/balancer"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/breaker"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/providers"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+func (h *AdminHandler) registerBackendRoutes(router *mux.Router) {
+	router.HandleFunc("/admin/tenants/{id:[0-9]+}/backends", h.ListTenantBackends).Methods("GET")
+	router.HandleFunc("/admin/tenants/{id:[0-9]+}/backends", h.CreateTenantBackend).Methods("POST")
+	router.HandleFunc("/admin/tenants/{id:[0-9]+}/backends/health", h.GetTenantBackendHealth).Methods("GET")
+	router.HandleFunc("/admin/tenants/{id:[0-9]+}/fallbacks", h.GetTenantFallbacks).Methods("GET")
+	router.HandleFunc("/admin/tenants/{id:[0-9]+}/fallbacks", h.PutTenantFallbacks).Methods("PUT")
+	router.HandleFunc("/admin/circuit-breakers/events", h.ListCircuitBreakerEvents).Methods("GET")
+	router.HandleFunc("/admin/tenants/{id:[0-9]+}/backends/{backendID:[0-9]+}", h.UpdateTenantBackend).Methods("PUT")
+	router.HandleFunc("/admin/tenants/{id:[0-9]+}/backends/{backendID:[0-9]+}", h.DeleteTenantBackend).Methods("DELETE")
+}
+
+// ListTenantBackends shows a tenant's pool and how it's balanced. A tenant
+// without enabled backends sends everything to its backend_url.
+func (h *AdminHandler) ListTenantBackends(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	tenant, err := h.db.GetTenantByID(r.Context(), tenantID)
+	if err != nil {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	backends, err := h.db.ListTenantBackends(r.Context(), tenantID, false)
+	if err != nil {
+		log.Printf("Failed to list backends: %v", err)
+		http.Error(w, "Failed to list backends", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"backend_url":    tenant.BackendURL,
+		"lb_strategy":    tenant.LBStrategy,
+		"lb_hash_header": tenant.LBHashHeader,
+		"backends":       backends,
+	})
+}
+
+func (h *AdminHandler) CreateTenantBackend(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	backend := models.TenantBackend{Weight: 1, Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&backend); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	backend.TenantID = tenantID
+	if msg := validateBackend(&backend); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.db.GetTenantByID(r.Context(), tenantID); errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.db.CreateTenantBackend(r.Context(), &backend); err != nil {
+		log.Printf("Failed to create backend: %v", err)
+		http.Error(w, "Failed to create backend", http.StatusInternalServerError)
+		return
+	}
+	h.routes.Forget(tenantID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(backend)
+}
+
+// UpdateTenantBackend changes a backend's URL, weight or enabled flag.
+// Fields left out keep their current value.
+func (h *AdminHandler) UpdateTenantBackend(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID, _ := strconv.Atoi(vars["id"])
+	backendID, _ := strconv.Atoi(vars["backendID"])
+
+	backend, err := h.db.GetTenantBackend(r.Context(), tenantID, backendID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Backend not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get backend: %v", err)
+		http.Error(w, "Failed to update backend", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(backend); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	backend.ID = backendID
+	backend.TenantID = tenantID
+	if msg := validateBackend(backend); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.UpdateTenantBackend(r.Context(), backend); err != nil {
+		log.Printf("Failed to update backend: %v", err)
+		http.Error(w, "Failed to update backend", http.StatusInternalServerError)
+		return
+	}
+	h.routes.Forget(tenantID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(backend)
+}
+
+func (h *AdminHandler) DeleteTenantBackend(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID, _ := strconv.Atoi(vars["id"])
+	backendID, _ := strconv.Atoi(vars["backendID"])
+
+	err := h.db.DeleteTenantBackend(r.Context(), tenantID, backendID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Backend not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete backend: %v", err)
+		http.Error(w, "Failed to delete backend", http.StatusInternalServerError)
+		return
+	}
+	h.routes.Forget(tenantID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTenantBackendHealth reports whether each of a tenant's backends is in
+// rotation, as seen by this gateway instance.
+func (h *AdminHandler) GetTenantBackendHealth(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	tenant, err := h.db.GetTenantByID(r.Context(), tenantID)
+	if err != nil {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	backends, err := h.db.ListTenantBackends(r.Context(), tenantID, false)
+	if err != nil {
+		log.Printf("Failed to list backends: %v", err)
+		http.Error(w, "Failed to get backend health", http.StatusInternalServerError)
+		return
+	}
+
+	type backendHealth struct {
+		ID      int    `json:"id,omitempty"`
+		URL     string `json:"url"`
+		Enabled bool   `json:"enabled"`
+		balancer.BackendHealth
+		Circuit breaker.Stats `json:"circuit"`
+	}
+
+	// backend_url only serves traffic while no pool backend is enabled
+	health := []backendHealth{}
+	usesDefault := true
+	for _, b := range backends {
+		health = append(health, backendHealth{ID: b.ID, URL: b.URL, Enabled: b.Enabled, BackendHealth: h.health.Status(b.URL), Circuit: h.breakers.Stats(b.URL)})
+		usesDefault = usesDefault && !b.Enabled
+	}
+	if usesDefault {
+		health = append(health, backendHealth{URL: tenant.BackendURL, Enabled: true, BackendHealth: h.health.Status(tenant.BackendURL), Circuit: h.breakers.Stats(tenant.BackendURL)})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(health)
+}
+
+// ListCircuitBreakerEvents returns the latest circuit breaker transitions of
+// every gateway instance, optionally filtered with ?backend=<url>.
+func (h *AdminHandler) ListCircuitBreakerEvents(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	var err error
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > 1000 {
+			http.Error(w, "limit must be between 1 and 1000", http.StatusBadRequest)
+			return
+		}
+	}
+
+	events, err := h.db.ListCircuitBreakerEvents(r.Context(), r.URL.Query().Get("backend"), limit)
+	if err != nil {
+		log.Printf("Failed to list circuit breaker events: %v", err)
+		http.Error(w, "Failed to list circuit breaker events", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+func (h *AdminHandler) GetTenantFallbacks(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	fallbacks, err := h.db.ListTenantFallbacks(r.Context(), tenantID, false)
+	if err != nil {
+		log.Printf("Failed to list fallbacks: %v", err)
+		http.Error(w, "Failed to list fallbacks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fallbacks)
+}
+
+// PutTenantFallbacks replaces a tenant's fallback chain with the steps in
+// the body, tried in the order given. An empty list removes the chain.
+func (h *AdminHandler) PutTenantFallbacks(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var steps []struct {
+		Provider  string `json:"provider"`
+		URL       string `json:"url"`
+		Model     string `json:"model"`
+		APIFormat string `json:"api_format"`
+		TimeoutMs int    `json:"timeout_ms"`
+		Enabled   *bool  `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&steps); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	fallbacks := make([]models.TenantFallback, 0, len(steps))
+	for i, step := range steps {
+		if step.Provider == "" {
+			http.Error(w, fmt.Sprintf("step %d: provider is required", i+1), http.StatusBadRequest)
+			return
+		}
+		if !validUpstreamURL(step.URL) {
+			http.Error(w, fmt.Sprintf("step %d: url must be an absolute http(s) URL", i+1), http.StatusBadRequest)
+			return
+		}
+		if step.APIFormat == "" {
+			step.APIFormat = string(providers.OpenAI)
+		}
+		if !providers.Format(step.APIFormat).Valid() {
+			http.Error(w, fmt.Sprintf("step %d: %s", i+1, apiFormatError), http.StatusBadRequest)
+			return
+		}
+		if step.TimeoutMs == 0 {
+			step.TimeoutMs = 30000
+		}
+		if step.TimeoutMs < 0 {
+			http.Error(w, fmt.Sprintf("step %d: timeout_ms must be positive", i+1), http.StatusBadRequest)
+			return
+		}
+		fallbacks = append(fallbacks, models.TenantFallback{
+			Provider:  step.Provider,
+			URL:       step.URL,
+			Model:     step.Model,
+			APIFormat: step.APIFormat,
+			TimeoutMs: step.TimeoutMs,
+			Enabled:   step.Enabled == nil || *step.Enabled,
+		})
+	}
+
+	if _, err := h.db.GetTenantByID(r.Context(), tenantID); errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.db.ReplaceTenantFallbacks(r.Context(), tenantID, fallbacks); err != nil {
+		log.Printf("Failed to update fallbacks: %v", err)
+		http.Error(w, "Failed to update fallbacks", http.StatusInternalServerError)
+		return
+	}
+	h.routes.Forget(tenantID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(fallbacks)
+}
+
+const apiFormatError = "api_format must be openai, anthropic or gemini"
+
+func validUpstreamURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validateBackend returns what's wrong with a backend, or "" if nothing is.
+func validateBackend(backend *models.TenantBackend) string {
+	if !validUpstreamURL(backend.URL) {
+		return "url must be an absolute http(s) URL"
+	}
+	if backend.Weight <= 0 {
+		return "weight must be positive"
+	}
+	if backend.HealthCheckPath != "" && !strings.HasPrefix(backend.HealthCheckPath, "/") {
+		return "health_check_path must start with /"
+	}
+	return ""
+}
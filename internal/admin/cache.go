@@ -0,0 +1,258 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/cache"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/db"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+func (h *AdminHandler) registerCacheRoutes(router *mux.Router) {
+	router.HandleFunc("/admin/tenants/{id}/cache", h.ListCacheEntries).Methods("GET")
+	router.HandleFunc("/admin/tenants/{id}/cache", h.PurgeCache).Methods("DELETE")
+	router.HandleFunc("/admin/tenants/{id}/cache/invalidate", h.InvalidateCache).Methods("POST")
+	router.HandleFunc("/admin/tenants/{id}/cache/import", h.ImportCache).Methods("POST")
+	router.HandleFunc("/admin/tenants/{id}/cache/export", h.ExportCache).Methods("GET")
+	router.HandleFunc("/admin/tenants/{id}/cache/keys/rotate", h.RotateCacheKey).Methods("POST")
+	router.HandleFunc("/admin/tenants/{id}/cache/hash/{hash}", h.DeleteCacheEntryByHash).Methods("DELETE")
+	router.HandleFunc("/admin/tenants/{id}/cache/{entryID:[0-9]+}", h.GetCacheEntry).Methods("GET")
+	router.HandleFunc("/admin/tenants/{id}/cache/{entryID:[0-9]+}", h.DeleteCacheEntry).Methods("DELETE")
+}
+
+func (h *AdminHandler) ListCacheEntries(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	// Pagination params, e.g. ?limit=50&offset=100&q=refund
+	limit, offset := 50, 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > 500 {
+			http.Error(w, "limit must be between 1 and 500", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			http.Error(w, "offset must not be negative", http.StatusBadRequest)
+			return
+		}
+	}
+	search := r.URL.Query().Get("q")
+
+	entries, total, err := h.cache.ListEntries(r.Context(), tenantID, search, limit, offset)
+	if err != nil {
+		log.Printf("Failed to list cache entries: %v", err)
+		http.Error(w, "Failed to list cache entries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": entries,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+func (h *AdminHandler) GetCacheEntry(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+	entryID, err := strconv.ParseInt(vars["entryID"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid cache entry ID", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.cache.GetEntry(r.Context(), tenantID, entryID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Cache entry not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to get cache entry", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+func (h *AdminHandler) DeleteCacheEntry(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	entryID, err := strconv.ParseInt(vars["entryID"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid cache entry ID", http.StatusBadRequest)
+		return
+	}
+
+	h.invalidate(w, r, db.CacheEntryFilter{ID: entryID})
+}
+
+func (h *AdminHandler) DeleteCacheEntryByHash(w http.ResponseWriter, r *http.Request) {
+	h.invalidate(w, r, db.CacheEntryFilter{PromptHash: mux.Vars(r)["hash"]})
+}
+
+func (h *AdminHandler) InvalidateCache(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Prefix string `json:"prefix"`
+		Regex  string `json:"regex"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	// Validate inputs, an empty filter would wipe the whole cache
+	if req.Prefix == "" && req.Regex == "" {
+		http.Error(w, "prefix or regex is required", http.StatusBadRequest)
+		return
+	}
+	if req.Regex != "" {
+		if _, err := regexp.Compile(req.Regex); err != nil {
+			http.Error(w, "Invalid regex: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.invalidate(w, r, db.CacheEntryFilter{Prefix: req.Prefix, Regex: req.Regex})
+}
+
+func (h *AdminHandler) PurgeCache(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.cache.Purge(r.Context(), tenantID)
+	if err != nil {
+		log.Printf("Failed to purge cache for tenant %d: %v", tenantID, err)
+		http.Error(w, "Failed to purge cache", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "purged",
+		"deleted": deleted,
+	})
+}
+
+func (h *AdminHandler) ImportCache(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.db.GetTenantByID(r.Context(), tenantID); err != nil {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	// Body is JSONL, e.g. ?dry_run=true&overwrite=true
+	opts := cache.ImportOptions{
+		DryRun:    r.URL.Query().Get("dry_run") == "true",
+		Overwrite: r.URL.Query().Get("overwrite") == "true",
+	}
+
+	report, err := h.cache.Import(r.Context(), tenantID, r.Body, opts)
+	if err != nil {
+		log.Printf("Cache import for tenant %d failed: %v", tenantID, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":  "Cache import failed: " + err.Error(),
+			"report": report,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *AdminHandler) ExportCache(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="tenant-%d-cache.jsonl"`, tenantID))
+
+	count, err := h.cache.Export(r.Context(), tenantID, w)
+	if err != nil {
+		// Headers are already sent, all we can do is log and cut the stream short
+		log.Printf("Cache export for tenant %d failed after %d entries: %v", tenantID, count, err)
+	}
+}
+
+func (h *AdminHandler) RotateCacheKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	rotation, err := h.cache.RotateDataKey(r.Context(), tenantID)
+	if errors.Is(err, cache.ErrNoKeyManagement) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Cache key rotation for tenant %d failed: %v", tenantID, err)
+		http.Error(w, "Failed to rotate cache key", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rotation)
+}
+
+func (h *AdminHandler) invalidate(w http.ResponseWriter, r *http.Request, filter db.CacheEntryFilter) {
+	tenantID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.cache.Invalidate(r.Context(), tenantID, filter)
+	if err != nil {
+		log.Printf("Failed to invalidate cache for tenant %d: %v", tenantID, err)
+		http.Error(w, "Failed to invalidate cache", http.StatusInternalServerError)
+		return
+	}
+
+	if deleted == 0 && (filter.ID != 0 || filter.PromptHash != "") {
+		http.Error(w, "Cache entry not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "invalidated",
+		"deleted": deleted,
+	})
+}
@@ -0,0 +1,329 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/vault"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+func (h *AdminHandler) registerCredentialRoutes(router *mux.Router) {
+	router.HandleFunc("/admin/tenants/{id:[0-9]+}/credentials", h.ListTenantCredentials).Methods("GET")
+	router.HandleFunc("/admin/tenants/{id:[0-9]+}/credentials", h.CreateTenantCredential).Methods("POST")
+	router.HandleFunc("/admin/tenants/{id:[0-9]+}/credentials/{credID:[0-9]+}", h.UpdateTenantCredential).Methods("PUT")
+	router.HandleFunc("/admin/tenants/{id:[0-9]+}/credentials/{credID:[0-9]+}", h.DeleteTenantCredential).Methods("DELETE")
+	router.HandleFunc("/admin/tenants/{id:[0-9]+}/credentials/{credID:[0-9]+}/rotate", h.RotateTenantCredential).Methods("POST")
+}
+
+// ListTenantCredentials shows how a tenant's credentials are injected, with
+// only a hint of each secret.
+func (h *AdminHandler) ListTenantCredentials(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	creds, err := h.db.ListTenantCredentials(r.Context(), tenantID)
+	if err != nil {
+		log.Printf("Failed to list credentials: %v", err)
+		http.Error(w, "Failed to list credentials", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(creds)
+}
+
+func (h *AdminHandler) CreateTenantCredential(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		models.TenantCredential
+		Secret string `json:"secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	cred := req.TenantCredential
+	cred.TenantID = tenantID
+	if msg := validateCredential(&cred); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if msg := validateSecret(req.Secret); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.db.GetTenantByID(r.Context(), tenantID); errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to get tenant: %v", err)
+		http.Error(w, "Failed to create credential", http.StatusInternalServerError)
+		return
+	}
+	if taken, err := h.credentialNameTaken(r, &cred); err != nil || taken {
+		if err != nil {
+			log.Printf("Failed to list credentials: %v", err)
+			http.Error(w, "Failed to create credential", http.StatusInternalServerError)
+		} else {
+			http.Error(w, "A credential with this name already exists", http.StatusConflict)
+		}
+		return
+	}
+
+	if err := h.vault.Seal(r.Context(), &cred, req.Secret); errors.Is(err, vault.ErrNoKeyManagement) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Failed to seal credential: %v", err)
+		http.Error(w, "Failed to create credential", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.db.CreateTenantCredential(r.Context(), &cred); err != nil {
+		log.Printf("Failed to create credential: %v", err)
+		http.Error(w, "Failed to create credential", http.StatusInternalServerError)
+		return
+	}
+	h.vault.Forget(tenantID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(cred)
+}
+
+// UpdateTenantCredential changes where and how a credential is injected.
+// Fields left out keep their current value; the secret only changes through
+// rotation.
+func (h *AdminHandler) UpdateTenantCredential(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+	credID, err := strconv.Atoi(vars["credID"])
+	if err != nil {
+		http.Error(w, "Invalid credential ID", http.StatusBadRequest)
+		return
+	}
+
+	cred, err := h.db.GetTenantCredential(r.Context(), tenantID, credID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Credential not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get credential: %v", err)
+		http.Error(w, "Failed to update credential", http.StatusInternalServerError)
+		return
+	}
+
+	stored := *cred
+	req := struct {
+		*models.TenantCredential
+		Secret string `json:"secret"`
+	}{TenantCredential: cred}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.Secret != "" {
+		http.Error(w, "Use the rotate endpoint to change the secret", http.StatusBadRequest)
+		return
+	}
+	cred.ID = credID
+	cred.TenantID = tenantID
+	cred.SecretHint, cred.Version = stored.SecretHint, stored.Version
+	cred.CreatedAt, cred.RotatedAt = stored.CreatedAt, stored.RotatedAt
+	if msg := validateCredential(cred); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if taken, err := h.credentialNameTaken(r, cred); err != nil || taken {
+		if err != nil {
+			log.Printf("Failed to list credentials: %v", err)
+			http.Error(w, "Failed to update credential", http.StatusInternalServerError)
+		} else {
+			http.Error(w, "A credential with this name already exists", http.StatusConflict)
+		}
+		return
+	}
+
+	// The name and injection rule are bound into the sealed secret
+	if err := h.vault.Reseal(r.Context(), &stored, cred); errors.Is(err, vault.ErrNoKeyManagement) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Failed to reseal credential: %v", err)
+		http.Error(w, "Failed to update credential", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.db.UpdateTenantCredential(r.Context(), cred); err != nil {
+		log.Printf("Failed to update credential: %v", err)
+		http.Error(w, "Failed to update credential", http.StatusInternalServerError)
+		return
+	}
+	h.vault.Forget(tenantID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cred)
+}
+
+// RotateTenantCredential replaces a credential's secret. Requests already
+// in flight finish with the old one; other gateway instances pick up the new
+// one within CREDENTIAL_CACHE_TTL.
+func (h *AdminHandler) RotateTenantCredential(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+	credID, err := strconv.Atoi(vars["credID"])
+	if err != nil {
+		http.Error(w, "Invalid credential ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Secret string `json:"secret"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if msg := validateSecret(req.Secret); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	cred, err := h.db.GetTenantCredential(r.Context(), tenantID, credID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Credential not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get credential: %v", err)
+		http.Error(w, "Failed to rotate credential", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.vault.Seal(r.Context(), cred, req.Secret); errors.Is(err, vault.ErrNoKeyManagement) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Failed to seal credential: %v", err)
+		http.Error(w, "Failed to rotate credential", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.db.RotateTenantCredential(r.Context(), cred); err != nil {
+		log.Printf("Failed to rotate credential: %v", err)
+		http.Error(w, "Failed to rotate credential", http.StatusInternalServerError)
+		return
+	}
+	h.vault.Forget(tenantID)
+	log.Printf("🔑 Rotated credential %q of tenant %d to version %d", cred.Name, tenantID, cred.Version)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cred)
+}
+
+func (h *AdminHandler) DeleteTenantCredential(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenantID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid tenant ID", http.StatusBadRequest)
+		return
+	}
+	credID, err := strconv.Atoi(vars["credID"])
+	if err != nil {
+		http.Error(w, "Invalid credential ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.db.DeleteTenantCredential(r.Context(), tenantID, credID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Credential not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete credential: %v", err)
+		http.Error(w, "Failed to delete credential", http.StatusInternalServerError)
+		return
+	}
+	h.vault.Forget(tenantID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// credentialNameTaken reports whether another of the tenant's credentials
+// has cred's name.
+func (h *AdminHandler) credentialNameTaken(r *http.Request, cred *models.TenantCredential) (bool, error) {
+	creds, err := h.db.ListTenantCredentials(r.Context(), cred.TenantID)
+	if err != nil {
+		return false, err
+	}
+	for _, other := range creds {
+		if other.Name == cred.Name && other.ID != cred.ID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// validateCredential fills in defaults and returns what's wrong with a
+// credential's injection rule, or "" if nothing is.
+func validateCredential(cred *models.TenantCredential) string {
+	if cred.Location == "" {
+		cred.Location = vault.InHeader
+	}
+	if cred.Name == "" {
+		return "name is required"
+	}
+	// A credential for every backend would also reach fallbacks run by
+	// other providers
+	if !validUpstreamURL(cred.BackendURL) {
+		return "backend_url must be an absolute http(s) URL"
+	}
+	if cred.Location != vault.InHeader && cred.Location != vault.InQuery {
+		return "location must be header or query"
+	}
+	if cred.Param == "" || strings.ContainsAny(cred.Param, " \t\r\n:()<>@,;\\\"/[]?={}") {
+		return "param must be a header or query parameter name"
+	}
+	if cred.Location == vault.InHeader && strings.EqualFold(cred.Param, "Host") {
+		return "param can't be Host"
+	}
+	if strings.ContainsAny(cred.Prefix, "\r\n") {
+		return "prefix can't contain line breaks"
+	}
+	return ""
+}
+
+func validateSecret(secret string) string {
+	if secret == "" {
+		return "secret is required"
+	}
+	if strings.ContainsAny(secret, "\r\n") {
+		return "secret can't contain line breaks"
+	}
+	return ""
+}
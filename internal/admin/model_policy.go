@@ -0,0 +1,150 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/providers"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+func (h *AdminHandler) registerModelPolicyRoutes(router *mux.Router) {
+	router.HandleFunc("/admin/tenants/{id:[0-9]+}/model-policy", h.GetTenantModelPolicy).Methods("GET")
+	router.HandleFunc("/admin/tenants/{id:[0-9]+}/model-policy", h.PutTenantModelPolicy).Methods("PUT")
+	router.HandleFunc("/admin/tenants/{id:[0-9]+}/model-policy", h.DeleteTenantModelPolicy).Methods("DELETE")
+}
+
+func (h *AdminHandler) GetTenantModelPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	policy, err := h.db.GetTenantModelPolicy(r.Context(), tenantID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// No policy, every model is allowed and served by the tenant's pool
+		policy = &models.TenantModelPolicy{
+			TenantID:      tenantID,
+			AllowedModels: []string{},
+			DeniedModels:  []string{},
+			Aliases:       map[string]string{},
+			Routes:        []models.ModelRoute{},
+		}
+	} else if err != nil {
+		log.Printf("Failed to get model policy: %v", err)
+		http.Error(w, "Failed to get model policy", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// PutTenantModelPolicy replaces a tenant's model policy. It applies from
+// the tenant's next request.
+func (h *AdminHandler) PutTenantModelPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var policy models.TenantModelPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	policy.TenantID = tenantID
+	if msg := validateModelPolicy(&policy); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.db.GetTenantByID(r.Context(), tenantID); errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.db.PutTenantModelPolicy(r.Context(), &policy); err != nil {
+		log.Printf("Failed to update model policy: %v", err)
+		http.Error(w, "Failed to update model policy", http.StatusInternalServerError)
+		return
+	}
+	h.routes.Forget(tenantID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+func (h *AdminHandler) DeleteTenantModelPolicy(w http.ResponseWriter, r *http.Request) {
+	tenantID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	err := h.db.DeleteTenantModelPolicy(r.Context(), tenantID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Model policy not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete model policy: %v", err)
+		http.Error(w, "Failed to delete model policy", http.StatusInternalServerError)
+		return
+	}
+	h.routes.Forget(tenantID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateModelPolicy fills in empty lists and returns what's wrong with a
+// policy, or "" if nothing is.
+func validateModelPolicy(policy *models.TenantModelPolicy) string {
+	if policy.AllowedModels == nil {
+		policy.AllowedModels = []string{}
+	}
+	if policy.DeniedModels == nil {
+		policy.DeniedModels = []string{}
+	}
+	if policy.Aliases == nil {
+		policy.Aliases = map[string]string{}
+	}
+	if policy.Routes == nil {
+		policy.Routes = []models.ModelRoute{}
+	}
+
+	for _, pattern := range append(policy.AllowedModels, policy.DeniedModels...) {
+		if msg := validateModelPattern(pattern); msg != "" {
+			return msg
+		}
+	}
+	seen := make(map[string]string, len(policy.Aliases))
+	for alias, model := range policy.Aliases {
+		if alias == "" || model == "" || strings.HasSuffix(alias, "*") || strings.HasSuffix(model, "*") {
+			return "aliases must map model names to model names"
+		}
+		if other, ok := seen[strings.ToLower(alias)]; ok {
+			return fmt.Sprintf("aliases %q and %q differ only in case", min(alias, other), max(alias, other))
+		}
+		seen[strings.ToLower(alias)] = alias
+	}
+	for i, route := range policy.Routes {
+		if msg := validateModelPattern(route.Model); msg != "" {
+			return fmt.Sprintf("route %d: %s", i+1, msg)
+		}
+		if !validUpstreamURL(route.URL) {
+			return fmt.Sprintf("route %d: url must be an absolute http(s) URL", i+1)
+		}
+		if route.APIFormat != "" && !providers.Format(route.APIFormat).Valid() {
+			return fmt.Sprintf("route %d: %s", i+1, apiFormatError)
+		}
+	}
+	return ""
+}
+
+func validateModelPattern(pattern string) string {
+	if pattern == "" || pattern == "*" {
+		return "model patterns must name a model or a prefix ending in *"
+	}
+	if strings.Contains(strings.TrimSuffix(pattern, "*"), "*") {
+		return fmt.Sprintf("model pattern %q may only end in *", pattern)
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+func (h *AdminHandler) registerNamespaceRoutes(router *mux.Router) {
+	router.HandleFunc("/admin/cache/namespaces", h.ListCacheNamespaces).Methods("GET")
+	router.HandleFunc("/admin/cache/namespaces", h.CreateCacheNamespace).Methods("POST")
+	router.HandleFunc("/admin/cache/namespaces/{nsID:[0-9]+}", h.GetCacheNamespace).Methods("GET")
+	router.HandleFunc("/admin/cache/namespaces/{nsID:[0-9]+}", h.DeleteCacheNamespace).Methods("DELETE")
+	router.HandleFunc("/admin/cache/namespaces/{nsID:[0-9]+}/members/{id:[0-9]+}", h.PutCacheNamespaceMember).Methods("PUT")
+	router.HandleFunc("/admin/cache/namespaces/{nsID:[0-9]+}/members/{id:[0-9]+}", h.DeleteCacheNamespaceMember).Methods("DELETE")
+}
+
+func (h *AdminHandler) ListCacheNamespaces(w http.ResponseWriter, r *http.Request) {
+	namespaces, err := h.db.ListCacheNamespaces(r.Context())
+	if err != nil {
+		log.Printf("Failed to list cache namespaces: %v", err)
+		http.Error(w, "Failed to list cache namespaces", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(namespaces)
+}
+
+func (h *AdminHandler) CreateCacheNamespace(w http.ResponseWriter, r *http.Request) {
+	var namespace models.CacheNamespace
+	if err := json.NewDecoder(r.Body).Decode(&namespace); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if namespace.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	namespace.Members = nil
+
+	if err := h.db.CreateCacheNamespace(r.Context(), &namespace); err != nil {
+		log.Printf("Failed to create cache namespace: %v", err)
+		http.Error(w, "Failed to create cache namespace", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(namespace)
+}
+
+func (h *AdminHandler) GetCacheNamespace(w http.ResponseWriter, r *http.Request) {
+	namespaceID, _ := strconv.Atoi(mux.Vars(r)["nsID"])
+
+	namespace, err := h.db.GetCacheNamespace(r.Context(), namespaceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Cache namespace not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get cache namespace: %v", err)
+		http.Error(w, "Failed to get cache namespace", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(namespace)
+}
+
+// DeleteCacheNamespace removes a namespace together with every entry shared in it.
+func (h *AdminHandler) DeleteCacheNamespace(w http.ResponseWriter, r *http.Request) {
+	namespaceID, _ := strconv.Atoi(mux.Vars(r)["nsID"])
+
+	err := h.db.DeleteCacheNamespace(r.Context(), namespaceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Cache namespace not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete cache namespace: %v", err)
+		http.Error(w, "Failed to delete cache namespace", http.StatusInternalServerError)
+		return
+	}
+	h.cache.ForgetNamespaces()
+
+	if err := h.cache.DeleteNamespaceEmbeddings(r.Context(), namespaceID); err != nil {
+		log.Printf("⚠️  Failed to delete embeddings of namespace %d: %v", namespaceID, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PutCacheNamespaceMember grants a tenant read and/or write access to a namespace.
+// The tenant only uses it once shared_cache is enabled in its cache settings.
+func (h *AdminHandler) PutCacheNamespaceMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespaceID, _ := strconv.Atoi(vars["nsID"])
+	tenantID, _ := strconv.Atoi(vars["id"])
+
+	member := models.CacheNamespaceMember{CanRead: true}
+	if err := json.NewDecoder(r.Body).Decode(&member); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	member.NamespaceID = namespaceID
+	member.TenantID = tenantID
+
+	namespace, err := h.db.GetCacheNamespace(r.Context(), namespaceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Cache namespace not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get cache namespace: %v", err)
+		http.Error(w, "Failed to update namespace member", http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.db.GetTenantByID(r.Context(), tenantID); errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+	member.Namespace = namespace.Name
+
+	if err := h.db.PutCacheNamespaceMember(r.Context(), &member); err != nil {
+		log.Printf("Failed to update namespace member: %v", err)
+		http.Error(w, "Failed to update namespace member", http.StatusInternalServerError)
+		return
+	}
+	h.cache.ForgetNamespaces()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(member)
+}
+
+// DeleteCacheNamespaceMember revokes a tenant's access. Entries it already
+// shared stay in the namespace.
+func (h *AdminHandler) DeleteCacheNamespaceMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespaceID, _ := strconv.Atoi(vars["nsID"])
+	tenantID, _ := strconv.Atoi(vars["id"])
+
+	err := h.db.DeleteCacheNamespaceMember(r.Context(), namespaceID, tenantID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Namespace member not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete namespace member: %v", err)
+		http.Error(w, "Failed to delete namespace member", http.StatusInternalServerError)
+		return
+	}
+	h.cache.ForgetNamespaces()
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,266 @@
+package balancer
+
+import (
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Strategy decides which backend of a pool gets a request.
+type Strategy string
+
+const (
+	RoundRobin       Strategy = "round_robin"       // weighted, smooth round robin
+	LeastOutstanding Strategy = "least_outstanding" // fewest in-flight requests per unit of weight
+	LatencyEWMA      Strategy = "latency_ewma"      // lowest moving-average latency, scaled by load
+	ConsistentHash   Strategy = "consistent_hash"   // same header value, same backend
+)
+
+// Valid reports whether s is a known strategy.
+func (s Strategy) Valid() bool {
+	switch s {
+	case RoundRobin, LeastOutstanding, LatencyEWMA, ConsistentHash:
+		return true
+	}
+	return false
+}
+
+// Target is a backend requests can be sent to.
+type Target struct {
+	ID     int // zero for a tenant's plain backend_url
+	URL    string
+	Weight int
+}
+
+// Weight of a new sample in the latency moving average
+const ewmaAlpha = 0.3
+
+// Virtual nodes per unit of weight on the consistent hash ring
+const ringReplicas = 100
+
+// stats is what the pool knows about one backend's load.
+type stats struct {
+	outstanding int
+	ewma        time.Duration // zero until the first response
+	current     int           // smooth round robin state
+}
+
+type ringPoint struct {
+	hash   uint32
+	target int // index into targets
+}
+
+// Pool picks backends for one tenant and tracks their load. It's safe for
+// concurrent use.
+type Pool struct {
+	strategy   Strategy
+	hashHeader string
+	targets    []Target
+
+	// Shared with the pools the tenant had before, whose in-flight requests
+	// still report back into the same stats
+	mu    *sync.Mutex
+	stats map[string]*stats
+	ring  []ringPoint
+}
+
+func newPool(strategy Strategy, hashHeader string, targets []Target, previous *Pool) *Pool {
+	p := &Pool{
+		strategy:   strategy,
+		hashHeader: hashHeader,
+		targets:    targets,
+		mu:         &sync.Mutex{},
+		stats:      make(map[string]*stats, len(targets)),
+	}
+
+	// Keep what was learned about backends that are still in the pool
+	if previous != nil {
+		p.mu = previous.mu
+		p.mu.Lock()
+		for _, t := range targets {
+			p.stats[t.URL] = previous.stats[t.URL]
+		}
+		p.mu.Unlock()
+	}
+	for _, t := range targets {
+		if p.stats[t.URL] == nil {
+			p.stats[t.URL] = &stats{}
+		}
+	}
+
+	if strategy == ConsistentHash {
+		for i, t := range targets {
+			for r := 0; r < max(1, t.Weight)*ringReplicas; r++ {
+				p.ring = append(p.ring, ringPoint{hash: crc32.ChecksumIEEE([]byte(fmt.Sprintf("%s#%d", t.URL, r))), target: i})
+			}
+		}
+		sort.Slice(p.ring, func(i, j int) bool { return p.ring[i].hash < p.ring[j].hash })
+	}
+
+	return p
+}
+
+// Pick chooses a backend for r, skipping those for which skip returns true
+// (nil skips none). done must be called once the backend has answered, or
+// with zero if it was never reached. It returns false when every backend
+// was skipped.
+func (p *Pool) Pick(r *http.Request, skip func(Target) bool) (Target, func(elapsed time.Duration), bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	eligible := make([]int, 0, len(p.targets))
+	for i, t := range p.targets {
+		if skip == nil || !skip(t) {
+			eligible = append(eligible, i)
+		}
+	}
+	if len(eligible) == 0 {
+		return Target{}, nil, false
+	}
+
+	var chosen int
+	switch p.strategy {
+	case LeastOutstanding:
+		chosen = p.pickLeast(eligible, func(s *stats) float64 { return float64(s.outstanding) })
+	case LatencyEWMA:
+		chosen = p.pickLeast(eligible, func(s *stats) float64 {
+			return float64(s.ewma) * float64(s.outstanding+1)
+		})
+	case ConsistentHash:
+		key := ""
+		if p.hashHeader != "" {
+			key = r.Header.Get(p.hashHeader)
+		}
+		if key == "" {
+			chosen = p.pickRoundRobin(eligible)
+		} else {
+			chosen = p.pickHash(key, eligible)
+		}
+	default:
+		chosen = p.pickRoundRobin(eligible)
+	}
+
+	target := p.targets[chosen]
+	s := p.stats[target.URL]
+	s.outstanding++
+
+	var once sync.Once
+	done := func(elapsed time.Duration) {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			s.outstanding--
+			if elapsed <= 0 {
+				return
+			}
+			if s.ewma == 0 {
+				s.ewma = elapsed
+			} else {
+				s.ewma += time.Duration(ewmaAlpha * float64(elapsed-s.ewma))
+			}
+		})
+	}
+
+	return target, done, true
+}
+
+// pickRoundRobin is nginx's smooth weighted round robin: heavier backends
+// are picked more often without being picked in bursts.
+func (p *Pool) pickRoundRobin(eligible []int) int {
+	total := 0
+	best := -1
+	for _, i := range eligible {
+		weight := max(1, p.targets[i].Weight)
+		s := p.stats[p.targets[i].URL]
+		s.current += weight
+		total += weight
+		if best == -1 || s.current > p.stats[p.targets[best].URL].current {
+			best = i
+		}
+	}
+	p.stats[p.targets[best].URL].current -= total
+	return best
+}
+
+// pickLeast picks the backend with the lowest cost per unit of weight,
+// breaking ties by round robin.
+func (p *Pool) pickLeast(eligible []int, cost func(*stats) float64) int {
+	var tied []int
+	lowest := 0.0
+	for _, i := range eligible {
+		c := cost(p.stats[p.targets[i].URL]) / float64(max(1, p.targets[i].Weight))
+		switch {
+		case len(tied) == 0 || c < lowest:
+			tied = append(tied[:0], i)
+			lowest = c
+		case c == lowest:
+			tied = append(tied, i)
+		}
+	}
+	return p.pickRoundRobin(tied)
+}
+
+// pickHash walks the ring clockwise from the key's hash to the first
+// eligible backend.
+func (p *Pool) pickHash(key string, eligible []int) int {
+	allowed := make(map[int]bool, len(eligible))
+	for _, i := range eligible {
+		allowed[i] = true
+	}
+
+	h := crc32.ChecksumIEEE([]byte(key))
+	start := sort.Search(len(p.ring), func(i int) bool { return p.ring[i].hash >= h })
+	for n := 0; n < len(p.ring); n++ {
+		point := p.ring[(start+n)%len(p.ring)]
+		if allowed[point.target] {
+			return point.target
+		}
+	}
+	return eligible[0]
+}
+
+// Registry keeps one Pool per key, a tenant or one of its model routes,
+// rebuilt when the backends or strategy behind the key change.
+type Registry struct {
+	mu    sync.Mutex
+	pools map[string]*registeredPool
+}
+
+type registeredPool struct {
+	signature string
+	pool      *Pool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{pools: make(map[string]*registeredPool)}
+}
+
+// Pool returns the pool registered under key for the given configuration.
+func (reg *Registry) Pool(key string, strategy Strategy, hashHeader string, targets []Target) *Pool {
+	var sig strings.Builder
+	fmt.Fprintf(&sig, "%s|%s", strategy, hashHeader)
+	for _, t := range targets {
+		fmt.Fprintf(&sig, "|%d:%s:%d", t.ID, t.URL, t.Weight)
+	}
+	signature := sig.String()
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	current, ok := reg.pools[key]
+	if ok && current.signature == signature {
+		return current.pool
+	}
+
+	var previous *Pool
+	if ok {
+		previous = current.pool
+	}
+	pool := newPool(strategy, hashHeader, targets, previous)
+	reg.pools[key] = &registeredPool{signature: signature, pool: pool}
+	return pool
+}
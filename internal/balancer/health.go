@@ -0,0 +1,162 @@
+package balancer
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// HealthOptions controls when backends are taken out of rotation.
+type HealthOptions struct {
+	// Passive outlier detection: this many 5xx responses or timeouts in a
+	// row eject a backend for BaseEjection, doubled for every ejection after
+	// it without a success in between, up to MaxEjection. Zero disables it.
+	ConsecutiveFailures int
+	BaseEjection        time.Duration
+	MaxEjection         time.Duration
+
+	// Failed active probes in a row that take a backend out of rotation
+	// until a probe passes again
+	ProbeFailures int
+}
+
+// BackendHealth is what's known about a backend's health.
+type BackendHealth struct {
+	Healthy             bool       `json:"healthy"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Ejections           int        `json:"ejections"`
+	EjectedUntil        *time.Time `json:"ejected_until,omitempty"`
+	ProbeFailing        bool       `json:"probe_failing"`
+	LastProbeAt         *time.Time `json:"last_probe_at,omitempty"`
+	LastProbeError      string     `json:"last_probe_error,omitempty"`
+}
+
+type backendHealth struct {
+	consecutiveFailures int
+	ejections           int
+	ejectedUntil        time.Time
+
+	probeFailures  int
+	lastProbeAt    time.Time
+	lastProbeError string
+}
+
+// Health tracks the health of backends by URL, from the responses they
+// give (passive) and from probes (active). It's safe for concurrent use.
+type Health struct {
+	opts HealthOptions
+
+	mu       sync.Mutex
+	backends map[string]*backendHealth
+}
+
+func NewHealth(opts HealthOptions) *Health {
+	return &Health{opts: opts, backends: make(map[string]*backendHealth)}
+}
+
+// backend returns the state of a backend. Callers hold h.mu.
+func (h *Health) backend(url string) *backendHealth {
+	b, ok := h.backends[url]
+	if !ok {
+		b = &backendHealth{}
+		h.backends[url] = b
+	}
+	return b
+}
+
+func (h *Health) healthy(b *backendHealth, now time.Time) bool {
+	probeFailing := h.opts.ProbeFailures > 0 && b.probeFailures >= h.opts.ProbeFailures
+	return !probeFailing && !now.Before(b.ejectedUntil)
+}
+
+// Healthy reports whether a backend is in rotation.
+func (h *Health) Healthy(url string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	b, ok := h.backends[url]
+	return !ok || h.healthy(b, time.Now())
+}
+
+// Report records whether a request to a backend succeeded, ejecting it
+// after too many failures in a row.
+func (h *Health) Report(url string, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	b := h.backend(url)
+	now := time.Now()
+	if ok {
+		b.consecutiveFailures = 0
+		if !now.Before(b.ejectedUntil) {
+			b.ejections = 0
+		}
+		return
+	}
+
+	b.consecutiveFailures++
+	if h.opts.ConsecutiveFailures <= 0 || b.consecutiveFailures < h.opts.ConsecutiveFailures || now.Before(b.ejectedUntil) {
+		return
+	}
+
+	ejection := h.opts.BaseEjection << b.ejections
+	if ejection <= 0 || ejection > h.opts.MaxEjection {
+		ejection = h.opts.MaxEjection
+	}
+	b.ejections++
+	b.ejectedUntil = now.Add(ejection)
+	log.Printf("⛔ Backend %s ejected for %s after %d failures in a row", url, ejection, b.consecutiveFailures)
+	b.consecutiveFailures = 0
+}
+
+// ReportProbe records the result of an active health check.
+func (h *Health) ReportProbe(url string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	b := h.backend(url)
+	b.lastProbeAt = time.Now()
+	if err == nil {
+		if h.opts.ProbeFailures > 0 && b.probeFailures >= h.opts.ProbeFailures {
+			log.Printf("💚 Backend %s passed its health check, back in rotation", url)
+		}
+		b.probeFailures = 0
+		b.lastProbeError = ""
+		return
+	}
+
+	b.probeFailures++
+	b.lastProbeError = err.Error()
+	if b.probeFailures == h.opts.ProbeFailures {
+		log.Printf("💔 Backend %s failed %d health checks in a row, out of rotation: %v", url, b.probeFailures, err)
+	}
+}
+
+// Status returns what's known about a backend's health.
+func (h *Health) Status(url string) BackendHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	b, ok := h.backends[url]
+	if !ok {
+		return BackendHealth{Healthy: true}
+	}
+
+	now := time.Now()
+	status := BackendHealth{
+		Healthy:             h.healthy(b, now),
+		ConsecutiveFailures: b.consecutiveFailures,
+		Ejections:           b.ejections,
+		ProbeFailing:        h.opts.ProbeFailures > 0 && b.probeFailures >= h.opts.ProbeFailures,
+		LastProbeError:      b.lastProbeError,
+	}
+	if now.Before(b.ejectedUntil) {
+		until := b.ejectedUntil
+		status.EjectedUntil = &until
+	}
+	if !b.lastProbeAt.IsZero() {
+		probed := b.lastProbeAt
+		status.LastProbeAt = &probed
+	}
+	return status
+}
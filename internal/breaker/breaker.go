@@ -0,0 +1,306 @@
+package breaker
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// State is where a circuit breaker is in its cycle.
+type State string
+
+const (
+	Closed   State = "closed"    // calls go through
+	Open     State = "open"      // calls fail fast
+	HalfOpen State = "half_open" // a few trial calls decide whether to close again
+)
+
+// Outcome is how a call through a breaker went.
+type Outcome int
+
+const (
+	Success  Outcome = iota
+	Failure          // 5xx or timeout
+	Canceled         // the client gave up, which says nothing about the backend
+)
+
+// Options controls when breakers open and close.
+type Options struct {
+	Window      time.Duration // rolling window the rates are measured over
+	MinRequests int           // calls needed in the window before a breaker may open
+
+	FailureRate      float64       // share of failed calls that opens a breaker, zero disables
+	SlowCallDuration time.Duration // calls at least this slow count as slow, zero disables
+	SlowCallRate     float64       // share of slow calls that opens a breaker
+
+	OpenDuration  time.Duration // how long a breaker fails fast before trying again
+	HalfOpenCalls int           // trial calls that must all succeed to close it
+}
+
+// Transition is a breaker changing state.
+type Transition struct {
+	Backend string
+	From    State
+	To      State
+	Reason  string
+	At      time.Time
+}
+
+// Number of buckets the rolling window is split into
+const windowBuckets = 10
+
+type bucket struct {
+	start    int64 // bucket number since the epoch
+	calls    int
+	failures int
+	slow     int
+}
+
+// Breaker guards one backend. It's safe for concurrent use.
+type Breaker struct {
+	backend string
+	set     *Set
+
+	mu             sync.Mutex
+	state          State
+	since          time.Time
+	generation     int // bumped on every transition, so late results of older calls are ignored
+	buckets        [windowBuckets]bucket
+	trials         int // half-open calls let through
+	trialSuccesses int
+
+	rejected    int64
+	transitions map[State]int
+}
+
+// Allow asks whether a call may go through. If it may, done must be called
+// with its outcome and duration. If it may not, retryAfter is how long the
+// breaker expects to keep failing fast.
+func (b *Breaker) Allow() (done func(Outcome, time.Duration), retryAfter time.Duration, ok bool) {
+	b.mu.Lock()
+	now := time.Now()
+	var transition *Transition
+
+	if b.state == Open {
+		if wait := b.since.Add(b.set.opts.OpenDuration).Sub(now); wait > 0 {
+			b.rejected++
+			b.mu.Unlock()
+			return nil, wait, false
+		}
+		transition = b.transition(HalfOpen, fmt.Sprintf("open for %s", b.set.opts.OpenDuration), now)
+	}
+	if b.state == HalfOpen {
+		if b.trials >= b.set.opts.HalfOpenCalls {
+			b.rejected++
+			b.mu.Unlock()
+			b.set.notify(transition)
+			return nil, time.Second, false
+		}
+		b.trials++
+	}
+
+	generation := b.generation
+	b.mu.Unlock()
+	b.set.notify(transition)
+
+	var once sync.Once
+	return func(outcome Outcome, elapsed time.Duration) {
+		once.Do(func() { b.record(generation, outcome, elapsed) })
+	}, 0, true
+}
+
+// Ready reports whether Allow would let a call through right now.
+func (b *Breaker) Ready() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Open:
+		return !time.Now().Before(b.since.Add(b.set.opts.OpenDuration))
+	case HalfOpen:
+		return b.trials < b.set.opts.HalfOpenCalls
+	}
+	return true
+}
+
+func (b *Breaker) record(generation int, outcome Outcome, elapsed time.Duration) {
+	b.mu.Lock()
+	var transition *Transition
+	defer func() {
+		b.mu.Unlock()
+		b.set.notify(transition)
+	}()
+
+	if generation != b.generation {
+		return
+	}
+
+	opts := b.set.opts
+	now := time.Now()
+	slow := opts.SlowCallDuration > 0 && elapsed >= opts.SlowCallDuration
+
+	if outcome == Canceled {
+		if b.state == HalfOpen {
+			b.trials--
+		}
+		return
+	}
+
+	if b.state == HalfOpen {
+		switch {
+		case outcome == Failure:
+			transition = b.transition(Open, "trial call failed", now)
+		case slow:
+			transition = b.transition(Open, fmt.Sprintf("trial call took %s", elapsed.Round(time.Millisecond)), now)
+		default:
+			b.trialSuccesses++
+			if b.trialSuccesses >= opts.HalfOpenCalls {
+				transition = b.transition(Closed, fmt.Sprintf("%d trial calls succeeded", b.trialSuccesses), now)
+			}
+		}
+		return
+	}
+
+	bk := b.bucket(now)
+	bk.calls++
+	if outcome == Failure {
+		bk.failures++
+	}
+	if slow {
+		bk.slow++
+	}
+
+	calls, failures, slowCalls := b.window(now)
+	if calls < opts.MinRequests {
+		return
+	}
+	failureRate := float64(failures) / float64(calls)
+	slowRate := float64(slowCalls) / float64(calls)
+	switch {
+	case opts.FailureRate > 0 && failureRate >= opts.FailureRate:
+		transition = b.transition(Open, fmt.Sprintf("%.0f%% of %d calls failed", failureRate*100, calls), now)
+	case opts.SlowCallDuration > 0 && opts.SlowCallRate > 0 && slowRate >= opts.SlowCallRate:
+		transition = b.transition(Open, fmt.Sprintf("%.0f%% of %d calls took %s or longer", slowRate*100, calls, opts.SlowCallDuration), now)
+	}
+}
+
+// bucket returns the current bucket of the rolling window. Callers hold b.mu.
+func (b *Breaker) bucket(now time.Time) *bucket {
+	n := now.UnixNano() / int64(b.bucketWidth())
+	bk := &b.buckets[n%windowBuckets]
+	if bk.start != n {
+		*bk = bucket{start: n}
+	}
+	return bk
+}
+
+// window sums the calls of the rolling window. Callers hold b.mu.
+func (b *Breaker) window(now time.Time) (calls, failures, slow int) {
+	n := now.UnixNano() / int64(b.bucketWidth())
+	for _, bk := range b.buckets {
+		if n-bk.start < windowBuckets {
+			calls += bk.calls
+			failures += bk.failures
+			slow += bk.slow
+		}
+	}
+	return calls, failures, slow
+}
+
+func (b *Breaker) bucketWidth() time.Duration {
+	return max(time.Millisecond, b.set.opts.Window/windowBuckets)
+}
+
+// transition moves the breaker to a new state. Callers hold b.mu and pass
+// the result to Set.notify once they've released it.
+func (b *Breaker) transition(to State, reason string, now time.Time) *Transition {
+	t := &Transition{Backend: b.backend, From: b.state, To: to, Reason: reason, At: now}
+
+	b.state = to
+	b.since = now
+	b.generation++
+	b.trials = 0
+	b.trialSuccesses = 0
+	if to == Closed {
+		b.buckets = [windowBuckets]bucket{}
+	}
+	b.transitions[to]++
+	return t
+}
+
+// Stats is a breaker's state and counters.
+type Stats struct {
+	State       State         `json:"state"`
+	Since       *time.Time    `json:"since,omitempty"`
+	Calls       int           `json:"window_calls"`
+	Failures    int           `json:"window_failures"`
+	SlowCalls   int           `json:"window_slow_calls"`
+	Rejected    int64         `json:"rejected"`
+	Transitions map[State]int `json:"transitions"`
+}
+
+// Set keeps one breaker per backend URL.
+type Set struct {
+	opts         Options
+	onTransition func(Transition)
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewSet creates breakers with the given options. onTransition, if not nil,
+// is called on every state change.
+func NewSet(opts Options, onTransition func(Transition)) *Set {
+	return &Set{opts: opts, onTransition: onTransition, breakers: make(map[string]*Breaker)}
+}
+
+// Get returns the breaker of a backend.
+func (s *Set) Get(backend string) *Breaker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.breakers[backend]
+	if !ok {
+		b = &Breaker{backend: backend, set: s, state: Closed, transitions: make(map[State]int)}
+		s.breakers[backend] = b
+	}
+	return b
+}
+
+// Stats returns a backend's breaker state and counters, those of a closed
+// circuit without calls if no request went to it yet.
+func (s *Set) Stats(backend string) Stats {
+	s.mu.Lock()
+	b, ok := s.breakers[backend]
+	s.mu.Unlock()
+	if !ok {
+		return Stats{State: Closed, Transitions: map[State]int{}}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := Stats{State: b.state, Rejected: b.rejected, Transitions: make(map[State]int, len(b.transitions))}
+	if !b.since.IsZero() {
+		since := b.since
+		stats.Since = &since
+	}
+	if b.state == Closed {
+		stats.Calls, stats.Failures, stats.SlowCalls = b.window(time.Now())
+	}
+	for state, n := range b.transitions {
+		stats.Transitions[state] = n
+	}
+	return stats
+}
+
+func (s *Set) notify(t *Transition) {
+	if t == nil {
+		return
+	}
+	log.Printf("⚡ Circuit for %s %s -> %s: %s", t.Backend, t.From, t.To, t.Reason)
+	if s.onTransition != nil {
+		s.onTransition(*t)
+	}
+}
@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"context"
+	"log"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/pricing"
+)
+
+// Feedback controls how reports of bad hits change matching.
+type Feedback struct {
+	// Blacklist a prompt/entry pair after this many bad reports, 0 never does
+	BlacklistAfter int
+	// Raise the tenant's threshold once this many bad semantic hits it still
+	// allows have been reported, 0 never does
+	RaiseThresholdAfter int
+	// The threshold is never raised above this
+	MaxThreshold float64
+}
+
+// How far above the median bad similarity a raised threshold lands
+const thresholdMargin = 0.01
+
+// FeedbackResult describes what a bad-hit report changed.
+type FeedbackResult struct {
+	Event           *models.CacheHitEvent `json:"event"`
+	Blacklisted     bool                  `json:"blacklisted"`
+	Threshold       float64               `json:"similarity_threshold"`
+	ThresholdRaised bool                  `json:"threshold_raised"`
+}
+
+// recordHit stores a hit in the background so it can be reported later, along
+// with the tokens and cost the cached response's usage says it saved.
+func (sc *SemanticCache) recordHit(tenantID int, promptHash string, hit *Hit) {
+	event := &models.CacheHitEvent{
+		TenantID:        tenantID,
+		EntryID:         &hit.Entry.ID,
+		PromptHash:      promptHash,
+		EntryPromptHash: hit.Entry.PromptHash,
+		Similarity:      hit.Similarity,
+		Semantic:        hit.Semantic,
+		NamespaceID:     hit.Entry.NamespaceID,
+	}
+	if usage, ok := pricing.ParseUsage([]byte(hit.Entry.Response)); ok {
+		event.Model = usage.Model
+		event.PromptTokens = usage.PromptTokens
+		event.CompletionTokens = usage.CompletionTokens
+		if cost, ok := sc.prices.Cost(usage); ok {
+			event.CostSaved = &cost
+		}
+	}
+
+	go func() {
+		if err := sc.db.RecordCacheHit(context.Background(), event); err != nil {
+			log.Printf("⚠️  Failed to record cache hit: %v", err)
+		}
+	}()
+}
+
+// ReportBadHit records that the latest hit served from an entry was wrong.
+// A non-empty prompt, with the model it was sent to, picks the hit when the entry answered several prompts. Repeated reports blacklist the prompt/entry pair,
+// and enough of them across the tenant raise its similarity threshold.
+func (sc *SemanticCache) ReportBadHit(ctx context.Context, tenantID int, entryID int64, model, prompt string) (*FeedbackResult, error) {
+	var promptHash string
+	if prompt != "" {
+		promptHash = sc.HashPrompt(ctx, tenantID, model, prompt)
+	}
+
+	event, err := sc.db.ReportBadCacheHit(ctx, tenantID, entryID, promptHash)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := sc.policyFor(ctx, tenantID)
+	result := &FeedbackResult{Event: event, Threshold: policy.SimilarityThreshold}
+	if !event.Semantic {
+		return result, nil
+	}
+
+	if sc.feedback.BlacklistAfter > 0 {
+		count, err := sc.db.CountBadCacheHits(ctx, tenantID, event.PromptHash, event.EntryPromptHash)
+		if err != nil {
+			return result, err
+		}
+		if count >= sc.feedback.BlacklistAfter {
+			if err := sc.db.BlacklistCachePair(ctx, tenantID, event.PromptHash, event.EntryPromptHash); err != nil {
+				return result, err
+			}
+			result.Blacklisted = true
+			log.Printf("🚫 Blacklisted cache pair for tenant %d after %d bad reports", tenantID, count)
+		}
+	}
+
+	if sc.feedback.RaiseThresholdAfter > 0 {
+		// Only reports since the last adjustment that the current threshold still lets through
+		similarities, err := sc.db.BadSemanticHitSimilarities(ctx, tenantID, policy.SimilarityThreshold, sc.settingsFor(ctx, tenantID).ThresholdAdjustedAt)
+		if err != nil {
+			return result, err
+		}
+
+		if len(similarities) >= sc.feedback.RaiseThresholdAfter {
+			threshold := min(similarities[len(similarities)/2]+thresholdMargin, sc.feedback.MaxThreshold)
+			if threshold > policy.SimilarityThreshold {
+				if err := sc.db.RaiseSimilarityThreshold(ctx, tenantID, threshold); err != nil {
+					return result, err
+				}
+				sc.ForgetSettings(tenantID)
+				result.Threshold = threshold
+				result.ThresholdRaised = true
+				log.Printf("📈 Raised similarity threshold for tenant %d to %.4f after %d bad reports", tenantID, threshold, len(similarities))
+			}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// How often an instance waiting on another instance's fill re-checks the cache
+const fillPollInterval = 100 * time.Millisecond
+
+// Deletes the lock only if it still holds our token
+var releaseFillLock = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+func fillLockKey(tenantID int, promptHash string) string {
+	return fmt.Sprintf("fill:tenant:%d:prompt:%s", tenantID, promptHash)
+}
+
+// AcquireFillLock elects one gateway instance to fetch a prompt from the backend.
+// It returns false if another instance already holds the lock. If Redis is
+// unreachable the caller is allowed through so requests never block on it.
+func (sc *SemanticCache) AcquireFillLock(ctx context.Context, tenantID int, promptHash string, ttl time.Duration) (release func(), acquired bool) {
+	tokenBytes := make([]byte, 16)
+	rand.Read(tokenBytes)
+	token := hex.EncodeToString(tokenBytes)
+	key := fillLockKey(tenantID, promptHash)
+
+	ok, err := sc.redis.SetNX(ctx, key, token, ttl).Result()
+	if err != nil {
+		log.Printf("⚠️  Fill lock unavailable: %v", err)
+		return func() {}, true
+	}
+	if !ok {
+		return nil, false
+	}
+
+	return func() {
+		releaseFillLock.Run(context.Background(), sc.redis, []string{key}, token)
+	}, true
+}
+
+// WaitForFill polls for an exact-match entry while another instance holds the
+// fill lock. It gives up when the lock is released without an entry appearing,
+// or when ctx is done.
+func (sc *SemanticCache) WaitForFill(ctx context.Context, tenantID int, model, prompt string) (*Hit, bool) {
+	promptHash := sc.HashPrompt(ctx, tenantID, model, prompt)
+	key := fillLockKey(tenantID, promptHash)
+
+	ticker := time.NewTicker(fillPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-ticker.C:
+		}
+
+		if cached, err := sc.db.GetCachedResponse(ctx, tenantID, promptHash, 0); err == nil {
+			hit, err := sc.newHit(ctx, tenantID, cached, 1, false)
+			return hit, err == nil
+		}
+
+		if n, err := sc.redis.Exists(ctx, key).Result(); err != nil || n == 0 {
+			// Check once more, the holder stores before it releases
+			if cached, err := sc.db.GetCachedResponse(ctx, tenantID, promptHash, 0); err == nil {
+				hit, err := sc.newHit(ctx, tenantID, cached, 1, false)
+				return hit, err == nil
+			}
+			return nil, false
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+)
+
+// StartJanitor periodically deletes expired entries and trims every tenant's
+// and namespace's cache to its size limit. It stops when ctx is cancelled.
+func (sc *SemanticCache) StartJanitor(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sc.sweep(ctx)
+			}
+		}
+	}()
+}
+
+func (sc *SemanticCache) sweep(ctx context.Context) {
+	expired, err := sc.db.DeleteExpiredCacheEntries(ctx)
+	if err != nil {
+		log.Printf("❌ Cache janitor: failed to delete expired entries: %v", err)
+		return
+	}
+	sc.deleteEmbeddings(ctx, expired)
+	if len(expired) > 0 {
+		log.Printf("🧹 Cache janitor: removed %d expired entries", len(expired))
+	}
+
+	counts, err := sc.db.CountCacheEntriesByTenant(ctx)
+	if err != nil {
+		log.Printf("❌ Cache janitor: failed to count entries: %v", err)
+		return
+	}
+
+	for tenantID, count := range counts {
+		policy := sc.policyFor(ctx, tenantID)
+		if policy.MaxEntries > 0 && count > int64(policy.MaxEntries) {
+			sc.evict(ctx, tenantID, policy)
+		}
+	}
+
+	// Namespaces have no settings of their own and use the defaults
+	if sc.defaults.MaxEntries <= 0 {
+		return
+	}
+	namespaceCounts, err := sc.db.CountCacheEntriesByNamespace(ctx)
+	if err != nil {
+		log.Printf("❌ Cache janitor: failed to count namespace entries: %v", err)
+		return
+	}
+	for namespaceID, count := range namespaceCounts {
+		if count > int64(sc.defaults.MaxEntries) {
+			sc.evictNamespace(ctx, namespaceID)
+		}
+	}
+}
+
+// evict trims a tenant's cache down to the policy's size limit.
+func (sc *SemanticCache) evict(ctx context.Context, tenantID int, policy Policy) {
+	if policy.MaxEntries <= 0 {
+		return
+	}
+
+	evicted, err := sc.db.EvictCacheEntries(ctx, tenantID, policy.MaxEntries, policy.EvictionPolicy)
+	if err != nil {
+		log.Printf("❌ Cache eviction failed for tenant %d: %v", tenantID, err)
+		return
+	}
+	sc.deleteEmbeddings(ctx, evicted)
+	if len(evicted) > 0 {
+		log.Printf("🧹 Evicted %d cache entries for tenant %d (%s)", len(evicted), tenantID, policy.EvictionPolicy)
+	}
+}
+
+func (sc *SemanticCache) deleteEmbeddings(ctx context.Context, refs []models.CacheEntryRef) {
+	if len(refs) == 0 {
+		return
+	}
+
+	keys := make([]string, len(refs))
+	for i, ref := range refs {
+		keys[i] = refEmbeddingKey(ref)
+	}
+
+	if err := sc.redis.Del(ctx, keys...).Err(); err != nil {
+		log.Printf("⚠️  Failed to delete %d embeddings: %v", len(keys), err)
+	}
+}
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/db"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+)
+
+// Invalidate deletes a tenant's entries matching filter together with their
+// embeddings. The rows are only removed if the embeddings could be deleted too.
+func (sc *SemanticCache) Invalidate(ctx context.Context, tenantID int, filter db.CacheEntryFilter) (int, error) {
+	// Regexes use Go's syntax and encrypted prompts can't be matched in SQL
+	inGo := filter.Regex != ""
+	if !inGo && filter.Prefix != "" {
+		var err error
+		if inGo, err = sc.db.HasEncryptedCacheEntries(ctx, tenantID); err != nil {
+			return 0, err
+		}
+	}
+	if inGo {
+		var re *regexp.Regexp
+		if filter.Regex != "" {
+			var err error
+			if re, err = regexp.Compile(filter.Regex); err != nil {
+				return 0, err
+			}
+		}
+
+		matched, err := sc.matchEntries(ctx, tenantID, func(entry *models.SemanticCache) bool {
+			return strings.HasPrefix(entry.Prompt, filter.Prefix) && (re == nil || re.MatchString(entry.Prompt))
+		})
+		if err != nil {
+			return 0, err
+		}
+		if len(matched) == 0 {
+			return 0, nil
+		}
+
+		ids := make([]int64, len(matched))
+		for i, entry := range matched {
+			ids[i] = entry.ID
+		}
+		filter = db.CacheEntryFilter{IDs: ids}
+	}
+
+	deleted, err := sc.db.DeleteCacheEntries(ctx, tenantID, filter, func(refs []models.CacheEntryRef) error {
+		if len(refs) == 0 {
+			return nil
+		}
+		keys := make([]string, len(refs))
+		for i, ref := range refs {
+			keys[i] = refEmbeddingKey(ref)
+		}
+		return sc.redis.Del(ctx, keys...).Err()
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(deleted), nil
+}
+
+// Purge removes every entry and every embedding a tenant has, including
+// embeddings whose rows are already gone.
+func (sc *SemanticCache) Purge(ctx context.Context, tenantID int) (int, error) {
+	deleted, err := sc.db.DeleteCacheEntries(ctx, tenantID, db.CacheEntryFilter{}, func([]models.CacheEntryRef) error {
+		return sc.deleteTenantEmbeddings(ctx, tenantID)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(deleted), nil
+}
+
+func (sc *SemanticCache) deleteTenantEmbeddings(ctx context.Context, tenantID int) error {
+	pattern := fmt.Sprintf("embedding:tenant:%d:*", tenantID)
+
+	iter := sc.redis.Scan(ctx, 0, pattern, 500).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == 500 {
+			if err := sc.redis.Del(ctx, keys...).Err(); err != nil {
+				return err
+			}
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	if len(keys) > 0 {
+		return sc.redis.Del(ctx, keys...).Err()
+	}
+	return nil
+}
+
+// ListEntries pages through a tenant's decrypted cache entries, newest first.
+// A non-empty search restricts the result to prompts containing it.
+func (sc *SemanticCache) ListEntries(ctx context.Context, tenantID int, search string, limit, offset int) ([]models.SemanticCache, int64, error) {
+	// Rows keep the form they were stored in, whatever the tenant's current
+	// setting, so any encrypted one means searching in Go
+	encrypted := false
+	if search != "" {
+		var err error
+		if encrypted, err = sc.db.HasEncryptedCacheEntries(ctx, tenantID); err != nil {
+			return nil, 0, err
+		}
+	}
+	if encrypted {
+		search = strings.ToLower(search)
+		matched, err := sc.matchEntries(ctx, tenantID, func(entry *models.SemanticCache) bool {
+			return strings.Contains(strings.ToLower(entry.Prompt), search)
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+
+		// matchEntries walks oldest first
+		slices.Reverse(matched)
+		total := int64(len(matched))
+		if offset >= len(matched) {
+			return []models.SemanticCache{}, total, nil
+		}
+		return matched[offset:min(offset+limit, len(matched))], total, nil
+	}
+
+	entries, total, err := sc.db.ListCacheEntries(ctx, tenantID, search, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range entries {
+		if err := sc.open(ctx, &entries[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	return entries, total, nil
+}
+
+// GetEntry fetches one decrypted entry without counting it as a hit.
+func (sc *SemanticCache) GetEntry(ctx context.Context, tenantID int, id int64) (*models.SemanticCache, error) {
+	entry, err := sc.db.GetCacheEntry(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := sc.open(ctx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// matchEntries decrypts every entry of a tenant and returns those match accepts.
+func (sc *SemanticCache) matchEntries(ctx context.Context, tenantID int, match func(*models.SemanticCache) bool) ([]models.SemanticCache, error) {
+	var matched []models.SemanticCache
+	var afterID int64
+
+	for {
+		entries, err := sc.db.ListCacheEntriesAfter(ctx, tenantID, afterID, 500)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			return matched, nil
+		}
+		afterID = entries[len(entries)-1].ID
+
+		for i := range entries {
+			if err := sc.open(ctx, &entries[i]); err != nil {
+				return nil, err
+			}
+			if match(&entries[i]) {
+				matched = append(matched, entries[i])
+			}
+		}
+	}
+}
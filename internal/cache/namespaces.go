@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+)
+
+type cachedMemberships struct {
+	members  []models.CacheNamespaceMember
+	loadedAt time.Time
+}
+
+// namespaceStore is a short-lived in-memory copy of each tenant's namespace
+// memberships, like settingsStore.
+type namespaceStore struct {
+	mu      sync.Mutex
+	entries map[int]cachedMemberships
+}
+
+func namespaceEmbeddingKey(namespaceID int, model, promptHash string) string {
+	return fmt.Sprintf("embedding:namespace:%d:%sprompt:%s", namespaceID, modelSegment(model), promptHash)
+}
+
+// refEmbeddingKey returns the Redis key holding an entry's embedding.
+func refEmbeddingKey(ref models.CacheEntryRef) string {
+	if ref.NamespaceID != 0 {
+		return namespaceEmbeddingKey(ref.NamespaceID, ref.Model, ref.PromptHash)
+	}
+	return embeddingKey(ref.TenantID, ref.Model, ref.PromptHash)
+}
+
+// membershipsFor returns the namespaces a tenant may use. Tenants that haven't
+// opted in to shared caching, or that encrypt their cache, get none.
+func (sc *SemanticCache) membershipsFor(ctx context.Context, tenantID int) []models.CacheNamespaceMember {
+	if policy := sc.policyFor(ctx, tenantID); !policy.SharedCache || policy.EncryptAtRest {
+		return nil
+	}
+
+	sc.namespaces.mu.Lock()
+	cached, ok := sc.namespaces.entries[tenantID]
+	sc.namespaces.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < settingsTTL {
+		return cached.members
+	}
+
+	members, err := sc.db.ListTenantNamespaces(ctx, tenantID)
+	if err != nil {
+		log.Printf("⚠️  Cache namespaces unavailable for tenant %d: %v", tenantID, err)
+		return nil
+	}
+
+	sc.namespaces.mu.Lock()
+	sc.namespaces.entries[tenantID] = cachedMemberships{members: members, loadedAt: time.Now()}
+	sc.namespaces.mu.Unlock()
+
+	return members
+}
+
+// readableNamespaces returns the IDs of the namespaces a tenant reads from.
+func (sc *SemanticCache) readableNamespaces(ctx context.Context, tenantID int) []int {
+	var ids []int
+	for _, member := range sc.membershipsFor(ctx, tenantID) {
+		if member.CanRead {
+			ids = append(ids, member.NamespaceID)
+		}
+	}
+	return ids
+}
+
+func (sc *SemanticCache) namespaceName(ctx context.Context, tenantID, namespaceID int) string {
+	for _, member := range sc.membershipsFor(ctx, tenantID) {
+		if member.NamespaceID == namespaceID {
+			return member.Namespace
+		}
+	}
+	return ""
+}
+
+// ForgetNamespaces drops the in-memory memberships after a namespace changes.
+func (sc *SemanticCache) ForgetNamespaces() {
+	sc.namespaces.mu.Lock()
+	sc.namespaces.entries = make(map[int]cachedMemberships)
+	sc.namespaces.mu.Unlock()
+}
+
+// getNamespaceResponse looks a prompt up in the namespaces a tenant reads from.
+func (sc *SemanticCache) getNamespaceResponse(ctx context.Context, tenantID int, namespaceIDs []int, promptHash string, opts LookupOptions, similarity float64, semantic bool) (*Hit, error) {
+	cached, err := sc.db.GetNamespaceCachedResponse(ctx, namespaceIDs, promptHash, opts.MaxAge)
+	if err != nil {
+		return nil, err
+	}
+
+	hit, err := sc.newHit(ctx, tenantID, cached, similarity, semantic)
+	if err != nil {
+		return nil, err
+	}
+	hit.Namespace = sc.namespaceName(ctx, tenantID, *cached.NamespaceID)
+	return hit, nil
+}
+
+// publish copies a freshly stored entry into every namespace the tenant
+// writes to. Shared copies are never encrypted.
+func (sc *SemanticCache) publish(ctx context.Context, tenantID int, entry models.SemanticCache, vector []byte, policy Policy) {
+	for _, member := range sc.membershipsFor(ctx, tenantID) {
+		if !member.CanWrite {
+			continue
+		}
+
+		shared := entry
+		shared.TenantID = 0
+		shared.NamespaceID = &member.NamespaceID
+		shared.SourceTenantID = &tenantID
+		shared.EmbeddingStored = vector != nil
+
+		if err := sc.db.StoreCachedResponse(ctx, &shared, policy.TTL); err != nil {
+			log.Printf("❌ Failed to publish cache entry to namespace %s: %v", member.Namespace, err)
+			continue
+		}
+		if vector != nil {
+			sc.redis.Set(ctx, namespaceEmbeddingKey(member.NamespaceID, entry.Model, entry.PromptHash), vector, policy.TTL)
+		}
+
+		sc.evictNamespace(ctx, member.NamespaceID)
+	}
+}
+
+// Refreshable reports whether a tenant may replace the entry behind a hit:
+// its own entries, and shared ones in namespaces it writes to.
+func (sc *SemanticCache) Refreshable(ctx context.Context, tenantID int, hit *Hit) bool {
+	if hit.Entry.NamespaceID == nil {
+		return true
+	}
+	for _, member := range sc.membershipsFor(ctx, tenantID) {
+		if member.NamespaceID == *hit.Entry.NamespaceID {
+			return member.CanWrite
+		}
+	}
+	return false
+}
+
+// RefreshCachedResponse replaces the entry behind a stale hit with a fresh
+// response, in the tenant's own cache or in the namespace it came from.
+func (sc *SemanticCache) RefreshCachedResponse(ctx context.Context, tenantID int, hit *Hit, response *Response) error {
+	if hit.Entry.NamespaceID == nil {
+		// Store under the entry's own prompt so the row that was served gets
+		// refreshed, also when it was a semantic match
+		return sc.StoreCachedResponse(ctx, tenantID, hit.Entry.Model, hit.Entry.Prompt, response)
+	}
+	if !sc.Refreshable(ctx, tenantID, hit) {
+		return fmt.Errorf("tenant %d may not write to namespace %d", tenantID, *hit.Entry.NamespaceID)
+	}
+
+	// Same as publish: the writer's TTL, never encrypted (sharing tenants
+	// don't encrypt, membershipsFor makes sure of that)
+	policy := sc.policyFor(ctx, tenantID)
+	shared := &models.SemanticCache{
+		TenantID:         tenantID,
+		PromptHash:       hit.Entry.PromptHash,
+		Model:            hit.Entry.Model,
+		Prompt:           hit.Entry.Prompt,
+		NormalizedPrompt: hit.Entry.NormalizedPrompt,
+		Response:         string(response.Body),
+		StatusCode:       response.StatusCode,
+		ResponseHeaders:  response.Header,
+		ContentEncoding:  response.ContentEncoding,
+		EmbeddingStored:  hit.Entry.EmbeddingStored,
+	}
+	if err := sc.seal(ctx, shared); err != nil {
+		return err
+	}
+	shared.TenantID = 0
+	shared.NamespaceID = hit.Entry.NamespaceID
+	shared.SourceTenantID = &tenantID
+
+	if err := sc.db.StoreCachedResponse(ctx, shared, policy.TTL); err != nil {
+		return err
+	}
+
+	// The prompt didn't change, so neither did its embedding, which expires
+	// together with the row
+	key := namespaceEmbeddingKey(*shared.NamespaceID, shared.Model, shared.PromptHash)
+	if policy.TTL > 0 {
+		sc.redis.Expire(ctx, key, policy.TTL)
+	} else {
+		sc.redis.Persist(ctx, key)
+	}
+	return nil
+}
+
+// evictNamespace trims a namespace to the gateway's default size limit.
+func (sc *SemanticCache) evictNamespace(ctx context.Context, namespaceID int) {
+	if sc.defaults.MaxEntries <= 0 {
+		return
+	}
+
+	evicted, err := sc.db.EvictNamespaceEntries(ctx, namespaceID, sc.defaults.MaxEntries, sc.defaults.EvictionPolicy)
+	if err != nil {
+		log.Printf("❌ Cache eviction failed for namespace %d: %v", namespaceID, err)
+		return
+	}
+	sc.deleteEmbeddings(ctx, evicted)
+	if len(evicted) > 0 {
+		log.Printf("🧹 Evicted %d cache entries for namespace %d (%s)", len(evicted), namespaceID, sc.defaults.EvictionPolicy)
+	}
+}
+
+// DeleteNamespaceEmbeddings drops the embeddings of a deleted namespace; its
+// rows are removed with it by the database.
+func (sc *SemanticCache) DeleteNamespaceEmbeddings(ctx context.Context, namespaceID int) error {
+	pattern := fmt.Sprintf("embedding:namespace:%d:*", namespaceID)
+	keys, err := sc.redis.Keys(ctx, pattern).Result()
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+	return sc.redis.Del(ctx, keys...).Err()
+}
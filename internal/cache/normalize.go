@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	whitespaceRe       = regexp.MustCompile(`\s+`)
+	spaceBeforePunctRe = regexp.MustCompile(`\s+([.,!?;:])`)
+
+	// Fragments that differ between otherwise identical prompts. Only forms
+	// that can't be part of the question itself: bare dates, times and numbers
+	// may be exactly what is being asked about.
+	volatilePatterns = []struct {
+		re          *regexp.Regexp
+		placeholder string
+	}{
+		{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<uuid>"},
+		{regexp.MustCompile(`(?i)\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`), "<timestamp>"}, // RFC 3339 / ISO 8601
+	}
+)
+
+var normalizeSteps = map[string]func(string) string{
+	"trim": strings.TrimSpace,
+	"collapse_whitespace": func(s string) string {
+		return whitespaceRe.ReplaceAllString(s, " ")
+	},
+	"casefold": func(s string) string {
+		return cases.Fold().String(s)
+	},
+	"nfkc": norm.NFKC.String,
+	"tight_punctuation": func(s string) string {
+		return spaceBeforePunctRe.ReplaceAllString(s, "$1")
+	},
+	"strip_volatile": func(s string) string {
+		for _, p := range volatilePatterns {
+			s = p.re.ReplaceAllString(s, p.placeholder)
+		}
+		return s
+	},
+}
+
+// Normalizer rewrites prompts into a canonical form before they're hashed and
+// embedded, so trivially different prompts share a cache entry.
+type Normalizer struct {
+	steps []func(string) string
+
+	// Compiled tenant rules, keyed by pattern
+	rules sync.Map
+}
+
+// NewNormalizer builds a chain from step names, applied in order. Available
+// steps: trim, collapse_whitespace, casefold, nfkc, tight_punctuation and
+// strip_volatile (RFC 3339 timestamps and UUIDs).
+func NewNormalizer(names []string) (*Normalizer, error) {
+	n := &Normalizer{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		step, ok := normalizeSteps[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown normalization step %q", name)
+		}
+		n.steps = append(n.steps, step)
+	}
+	return n, nil
+}
+
+// Normalize runs the chain, then the tenant's regex rules.
+func (n *Normalizer) Normalize(prompt string, rules []models.NormalizationRule) string {
+	for _, step := range n.steps {
+		prompt = step(prompt)
+	}
+
+	for _, rule := range rules {
+		re, err := n.compile(rule.Pattern)
+		if err != nil {
+			continue // validated when saved, skip if it somehow isn't
+		}
+		prompt = re.ReplaceAllString(prompt, rule.Replacement)
+	}
+
+	return prompt
+}
+
+func (n *Normalizer) compile(pattern string) (*regexp.Regexp, error) {
+	if re, ok := n.rules.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	n.rules.Store(pattern, re)
+	return re, nil
+}
@@ -0,0 +1,21 @@
+package cache
+
+// Response is the envelope of a backend response kept in the cache: the
+// status, the upstream headers worth replaying, and the decoded body along with
+// the encoding it arrived in.
+type Response struct {
+	StatusCode      int
+	Header          map[string]string
+	Body            []byte
+	ContentEncoding string
+}
+
+// Response returns the cached envelope of a hit.
+func (h *Hit) Response() *Response {
+	return &Response{
+		StatusCode:      h.Entry.StatusCode,
+		Header:          h.Entry.ResponseHeaders,
+		Body:            []byte(h.Entry.Response),
+		ContentEncoding: h.Entry.ContentEncoding,
+	}
+}
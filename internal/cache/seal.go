@@ -0,0 +1,321 @@
+package cache
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/secrets"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Storage controls how entries are stored at rest.
+type Storage struct {
+	Keys            secrets.KeyWrapper // wraps tenant data keys, nil disables encryption
+	CompressMinSize int                // zstd-compress responses at least this large, 0 disables
+}
+
+// ErrNoKeyManagement is returned when a tenant requires encryption at rest
+// but the gateway has no key management configured.
+var ErrNoKeyManagement = errors.New("encryption at rest requires a key management provider")
+
+const compressionZstd = "zstd"
+
+// EncodeAll and DecodeAll are safe for concurrent use
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// keyStore keeps unwrapped data keys in memory, so the master key is only
+// used once per key and process.
+type keyStore struct {
+	mu     sync.Mutex
+	keys   map[[2]int][]byte // tenant ID, version
+	active map[int]activeKey
+}
+
+type activeKey struct {
+	version  int
+	loadedAt time.Time
+}
+
+// CanEncrypt reports whether key management is configured.
+func (sc *SemanticCache) CanEncrypt() bool {
+	return sc.storage.Keys != nil
+}
+
+// seal prepares an entry for storage: large responses are compressed and, if
+// the tenant encrypts at rest, prompts and response are encrypted with the
+// tenant's active data key. Each value is bound to its row and column.
+func (sc *SemanticCache) seal(ctx context.Context, entry *models.SemanticCache) error {
+	response := []byte(entry.Response)
+	entry.Compression = ""
+	entry.DataKeyVersion = nil
+
+	if minSize := sc.storage.CompressMinSize; minSize > 0 && len(response) >= minSize {
+		if compressed := zstdEncoder.EncodeAll(response, nil); len(compressed) < len(response) {
+			response = compressed
+			entry.Compression = compressionZstd
+		}
+	}
+
+	if !sc.policyFor(ctx, entry.TenantID).EncryptAtRest {
+		if entry.Compression != "" {
+			entry.Response = base64.StdEncoding.EncodeToString(response)
+		}
+		return nil
+	}
+
+	version, key, err := sc.activeDataKey(ctx, entry.TenantID)
+	if err != nil {
+		return err
+	}
+
+	fields := []struct {
+		name  string
+		value *string
+		plain []byte
+	}{
+		{"prompt", &entry.Prompt, []byte(entry.Prompt)},
+		{"normalized_prompt", &entry.NormalizedPrompt, []byte(entry.NormalizedPrompt)},
+		{"response", &entry.Response, response},
+	}
+	for _, field := range fields {
+		sealed, err := secrets.Seal(key, field.plain, sealAAD(entry, field.name))
+		if err != nil {
+			return err
+		}
+		*field.value = base64.StdEncoding.EncodeToString(sealed)
+	}
+	entry.DataKeyVersion = &version
+
+	return nil
+}
+
+// open reverses seal in place. Plaintext entries are left as they are.
+func (sc *SemanticCache) open(ctx context.Context, entry *models.SemanticCache) error {
+	if entry.DataKeyVersion == nil && entry.Compression == "" {
+		return nil
+	}
+
+	var response []byte
+	if entry.DataKeyVersion == nil {
+		decoded, err := base64.StdEncoding.DecodeString(entry.Response)
+		if err != nil {
+			return fmt.Errorf("cache entry %d: %w", entry.ID, err)
+		}
+		response = decoded
+	} else {
+		key, err := sc.dataKey(ctx, entry.TenantID, *entry.DataKeyVersion)
+		if err != nil {
+			return err
+		}
+
+		opened := make(map[string][]byte, 3)
+		for name, value := range map[string]string{
+			"prompt":            entry.Prompt,
+			"normalized_prompt": entry.NormalizedPrompt,
+			"response":          entry.Response,
+		} {
+			sealed, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return fmt.Errorf("cache entry %d: %s: %w", entry.ID, name, err)
+			}
+			if opened[name], err = secrets.Open(key, sealed, sealAAD(entry, name)); err != nil {
+				return fmt.Errorf("cache entry %d: %s: %w", entry.ID, name, err)
+			}
+		}
+		entry.Prompt = string(opened["prompt"])
+		entry.NormalizedPrompt = string(opened["normalized_prompt"])
+		response = opened["response"]
+	}
+
+	switch entry.Compression {
+	case "":
+	case compressionZstd:
+		decompressed, err := zstdDecoder.DecodeAll(response, nil)
+		if err != nil {
+			return fmt.Errorf("cache entry %d: %w", entry.ID, err)
+		}
+		response = decompressed
+	default:
+		return fmt.Errorf("cache entry %d: unknown compression %q", entry.ID, entry.Compression)
+	}
+
+	entry.Response = string(response)
+	entry.DataKeyVersion = nil
+	entry.Compression = ""
+	return nil
+}
+
+// Ciphertexts can't be moved between tenants, rows or columns
+func sealAAD(entry *models.SemanticCache, field string) []byte {
+	return []byte(fmt.Sprintf("tenant:%d:prompt:%s:%s", entry.TenantID, entry.PromptHash, field))
+}
+
+// activeDataKey returns the key new entries are encrypted with, creating the
+// tenant's first key if needed.
+func (sc *SemanticCache) activeDataKey(ctx context.Context, tenantID int) (int, []byte, error) {
+	if sc.storage.Keys == nil {
+		return 0, nil, ErrNoKeyManagement
+	}
+
+	sc.keys.mu.Lock()
+	active, ok := sc.keys.active[tenantID]
+	sc.keys.mu.Unlock()
+	if ok && time.Since(active.loadedAt) < settingsTTL {
+		key, err := sc.dataKey(ctx, tenantID, active.version)
+		return active.version, key, err
+	}
+
+	dataKey, err := sc.db.GetActiveDataKey(ctx, tenantID)
+	if err != nil {
+		if dataKey, err = sc.createDataKey(ctx, tenantID, false); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	key, err := sc.unwrap(ctx, dataKey)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	sc.keys.mu.Lock()
+	sc.keys.active[tenantID] = activeKey{version: dataKey.Version, loadedAt: time.Now()}
+	sc.keys.mu.Unlock()
+
+	return dataKey.Version, key, nil
+}
+
+// dataKey returns a specific version of a tenant's data key.
+func (sc *SemanticCache) dataKey(ctx context.Context, tenantID, version int) ([]byte, error) {
+	sc.keys.mu.Lock()
+	key, ok := sc.keys.keys[[2]int{tenantID, version}]
+	sc.keys.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	if sc.storage.Keys == nil {
+		return nil, ErrNoKeyManagement
+	}
+
+	dataKey, err := sc.db.GetDataKey(ctx, tenantID, version)
+	if err != nil {
+		return nil, fmt.Errorf("data key %d for tenant %d: %w", version, tenantID, err)
+	}
+	return sc.unwrap(ctx, dataKey)
+}
+
+func (sc *SemanticCache) unwrap(ctx context.Context, dataKey *models.DataKey) ([]byte, error) {
+	key, err := sc.storage.Keys.UnwrapKey(ctx, dataKey.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key %d for tenant %d: %w", dataKey.Version, dataKey.TenantID, err)
+	}
+
+	sc.keys.mu.Lock()
+	sc.keys.keys[[2]int{dataKey.TenantID, dataKey.Version}] = key
+	sc.keys.mu.Unlock()
+
+	return key, nil
+}
+
+func (sc *SemanticCache) createDataKey(ctx context.Context, tenantID int, rotate bool) (*models.DataKey, error) {
+	key, err := secrets.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := sc.storage.Keys.WrapKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := sc.db.CreateDataKey(ctx, tenantID, wrapped, rotate)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.keys.mu.Lock()
+	delete(sc.keys.active, tenantID)
+	sc.keys.mu.Unlock()
+
+	return dataKey, nil
+}
+
+// KeyRotation summarizes a data key rotation.
+type KeyRotation struct {
+	Version   *int `json:"version"` // the new active key, nil when the tenant doesn't encrypt
+	Rewritten int  `json:"rewritten"`
+	Failed    int  `json:"failed"`
+}
+
+// RotateDataKey gives a tenant a new data key and re-encrypts its entries with
+// it. Entries are also brought in line with the tenant's current setting, so
+// rotating after turning encryption off decrypts them.
+func (sc *SemanticCache) RotateDataKey(ctx context.Context, tenantID int) (*KeyRotation, error) {
+	report := &KeyRotation{}
+
+	if sc.policyFor(ctx, tenantID).EncryptAtRest {
+		if sc.storage.Keys == nil {
+			return nil, ErrNoKeyManagement
+		}
+		dataKey, err := sc.createDataKey(ctx, tenantID, true)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := sc.unwrap(ctx, dataKey); err != nil {
+			return nil, err
+		}
+
+		sc.keys.mu.Lock()
+		sc.keys.active[tenantID] = activeKey{version: dataKey.Version, loadedAt: time.Now()}
+		sc.keys.mu.Unlock()
+		report.Version = &dataKey.Version
+	}
+
+	var afterID int64
+	for {
+		entries, err := sc.db.ListCacheEntriesAfter(ctx, tenantID, afterID, 500)
+		if err != nil {
+			return report, err
+		}
+		if len(entries) == 0 {
+			return report, nil
+		}
+		afterID = entries[len(entries)-1].ID
+
+		for i := range entries {
+			entry := &entries[i]
+			if sameVersion(entry.DataKeyVersion, report.Version) {
+				continue
+			}
+
+			err := sc.open(ctx, entry)
+			if err == nil {
+				err = sc.seal(ctx, entry)
+			}
+			if err == nil {
+				err = sc.db.UpdateCacheEntryPayload(ctx, entry)
+			}
+			if err != nil {
+				log.Printf("⚠️  Re-encrypting cache entry %d for tenant %d failed: %v", entry.ID, tenantID, err)
+				report.Failed++
+				continue
+			}
+			report.Rewritten++
+		}
+	}
+}
+
+func sameVersion(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -1,30 +1,52 @@
 package cache
 
 import (
-	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"math"
-	"net/http"
+	"strings"
 	"time"
 
 	"github.com/HanTheDev/multi-tenant-api-gateway/internal/db"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/embedding"
 	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/pricing"
+	"github.com/jackc/pgx/v5"
 	"github.com/redis/go-redis/v9"
 )
 
 type SemanticCache struct {
-	db                  *db.DB
-	redis               *redis.Client
-	embeddingService    string
-	similarityThreshold float64
+	db         *db.DB
+	redis      *redis.Client
+	embedder   embedding.Embedder
+	defaults   Policy
+	normalizer *Normalizer
+	settings   settingsStore
+	storage    Storage
+	keys       keyStore
+	feedback   Feedback
+	namespaces namespaceStore
+	prices     pricing.Table
 }
 
-func NewSemanticCache(database *db.DB, redisURL, embeddingService string) (*SemanticCache, error) {
+// Policy controls how long a tenant's entries live and how many are kept.
+type Policy struct {
+	TTL            time.Duration // zero means entries never expire
+	SoftTTL        time.Duration // older entries are served stale and refreshed, zero disables
+	MaxEntries     int           // zero means unlimited
+	EvictionPolicy string        // "lru" or "lfu"
+	EncryptAtRest  bool          // encrypt prompts and responses with a per-tenant data key
+	SharedCache    bool          // read from and write to the tenant's cache namespaces
+
+	// Minimum similarity for a semantic hit
+	SimilarityThreshold float64
+}
+
+func NewSemanticCache(database *db.DB, redisURL string, embedder embedding.Embedder, normalizer *Normalizer, defaults Policy, storage Storage, feedback Feedback, prices pricing.Table) (*SemanticCache, error) {
 	opt, err := redis.ParseURL(redisURL)
 	if err != nil {
 		return nil, err
@@ -32,45 +54,164 @@ func NewSemanticCache(database *db.DB, redisURL, embeddingService string) (*Sema
 
 	client := redis.NewClient(opt)
 
+	if defaults.SimilarityThreshold <= 0 {
+		defaults.SimilarityThreshold = 0.85 // 85% similarity threshold
+	}
+
 	return &SemanticCache{
-		db:                  database,
-		redis:               client,
-		embeddingService:    embeddingService,
-		similarityThreshold: 0.85, // 85% similarity threshold
+		db:         database,
+		redis:      client,
+		embedder:   embedder,
+		defaults:   defaults,
+		normalizer: normalizer,
+		settings:   settingsStore{entries: make(map[int]cachedSettings)},
+		storage:    storage,
+		keys:       keyStore{keys: make(map[[2]int][]byte), active: make(map[int]activeKey)},
+		feedback:   feedback,
+		namespaces: namespaceStore{entries: make(map[int]cachedMemberships)},
+		prices:     prices,
 	}, nil
 }
 
-func (sc *SemanticCache) hashPrompt(prompt string) string {
-	hash := sha256.Sum256([]byte(prompt))
+func embeddingKey(tenantID int, model, promptHash string) string {
+	return fmt.Sprintf("embedding:tenant:%d:%sprompt:%s", tenantID, modelSegment(model), promptHash)
+}
+
+// modelSegment scopes embedding keys to a model, so semantic lookups only
+// compare prompts that were sent to the same one. Entries without a model
+// keep the original keys.
+func modelSegment(model string) string {
+	if model == "" {
+		return ""
+	}
+	return "model:" + model + ":"
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// embeddingPattern matches the embedding keys under prefix, e.g.
+// "embedding:tenant:1:", of the entries cached for model.
+func embeddingPattern(prefix, model string) string {
+	return prefix + globEscaper.Replace(modelSegment(model)) + "prompt:*"
+}
+
+// Normalize returns the canonical form of a prompt for a tenant, which is
+// what gets hashed and embedded.
+func (sc *SemanticCache) Normalize(ctx context.Context, tenantID int, prompt string) string {
+	return sc.normalizer.Normalize(prompt, sc.settingsFor(ctx, tenantID).NormalizationRules)
+}
+
+// HashPrompt returns the exact-match cache key for a prompt sent to model.
+func (sc *SemanticCache) HashPrompt(ctx context.Context, tenantID int, model, prompt string) string {
+	return hashNormalized(model, sc.Normalize(ctx, tenantID, prompt))
+}
+
+// hashNormalized hashes a normalized prompt together with its model, so the
+// same question asked of two models gets two entries.
+func hashNormalized(model, normalized string) string {
+	if model != "" {
+		normalized = model + "\x00" + normalized
+	}
+	hash := sha256.Sum256([]byte(normalized))
 	return fmt.Sprintf("%x", hash)
 }
 
-func (sc *SemanticCache) GetCachedResponse(ctx context.Context, tenantID int, prompt string) (string, bool, error) {
-	promptHash := sc.hashPrompt(prompt)
+// Mode selects which lookups GetCachedResponse may use.
+type Mode string
+
+const (
+	ModeSemantic Mode = "semantic" // exact hash first, then embedding similarity
+	ModeExact    Mode = "exact"    // exact hash only
+	ModeOff      Mode = "off"      // no caching at all
+)
+
+// LookupOptions are per-request overrides for a cache lookup.
+type LookupOptions struct {
+	Mode      Mode
+	Threshold float64       // similarity threshold override, 0 uses the default
+	MaxAge    time.Duration // ignore entries older than this, 0 accepts any age
+}
+
+// Hit describes a cached entry that answered a lookup.
+type Hit struct {
+	Entry      *models.SemanticCache
+	Similarity float64 // 1 for exact matches
+	Semantic   bool
+	Stale      bool   // past the tenant's soft TTL, should be refreshed
+	Namespace  string // shared namespace the entry came from, empty for the tenant's own cache
+}
+
+// Age reports how long ago the entry was cached.
+func (h *Hit) Age() time.Duration {
+	return time.Since(h.Entry.CreatedAt)
+}
+
+func (sc *SemanticCache) GetCachedResponse(ctx context.Context, tenantID int, model, prompt string, opts LookupOptions) (*Hit, bool, error) {
+	if opts.Mode == ModeOff {
+		return nil, false, nil
+	}
+
+	normalized := sc.Normalize(ctx, tenantID, prompt)
+	promptHash := hashNormalized(model, normalized)
 
 	// 1. Try exact match first (fastest)
-	cached, err := sc.db.GetCachedResponse(ctx, tenantID, promptHash)
+	cached, err := sc.cachedEntry(ctx, tenantID, promptHash, opts.MaxAge)
 	if err == nil {
 		log.Printf("✅ Exact hash match found!")
-		return cached.Response, true, nil
+		hit, err := sc.newHit(ctx, tenantID, cached, 1, false)
+		if err == nil {
+			sc.recordHit(tenantID, promptHash, hit)
+		}
+		return hit, err == nil, err
+	}
+
+	// Then the shared namespaces the tenant reads from
+	namespaceIDs := sc.readableNamespaces(ctx, tenantID)
+	if len(namespaceIDs) > 0 {
+		hit, err := sc.getNamespaceResponse(ctx, tenantID, namespaceIDs, promptHash, opts, 1, false)
+		if err == nil {
+			log.Printf("✅ Exact hash match found in namespace %s", hit.Namespace)
+			sc.recordHit(tenantID, promptHash, hit)
+			return hit, true, nil
+		}
+	}
+
+	if opts.Mode == ModeExact {
+		return nil, false, nil
+	}
+
+	threshold := sc.policyFor(ctx, tenantID).SimilarityThreshold
+	if opts.Threshold > 0 {
+		threshold = opts.Threshold
 	}
 
 	// 2. Try semantic search (only if embedding service is available)
-	queryEmbedding, err := sc.getEmbedding(prompt)
+	queryEmbedding, err := sc.getEmbedding(ctx, normalized)
 	if err != nil {
 		log.Printf("⚠️  Embedding service unavailable: %v", err)
-		return "", false, nil // Not an error, just skip semantic search
+		return nil, false, nil // Not an error, just skip semantic search
+	}
+
+	// Get all prompts cached for this model by the tenant and its namespaces from Redis
+	keys, err := sc.redis.Keys(ctx, embeddingPattern(fmt.Sprintf("embedding:tenant:%d:", tenantID), model)).Result()
+	if err != nil {
+		return nil, false, nil
+	}
+	for _, namespaceID := range namespaceIDs {
+		shared, err := sc.redis.Keys(ctx, embeddingPattern(fmt.Sprintf("embedding:namespace:%d:", namespaceID), model)).Result()
+		if err == nil {
+			keys = append(keys, shared...)
+		}
 	}
 
-	// Get all cached prompts for this tenant from Redis
-	pattern := fmt.Sprintf("embedding:tenant:%d:*", tenantID)
-	keys, err := sc.redis.Keys(ctx, pattern).Result()
+	// Entries reported as wrong answers to this prompt
+	blacklisted, err := sc.db.BlacklistedCacheHashes(ctx, tenantID, promptHash)
 	if err != nil {
-		return "", false, nil
+		log.Printf("⚠️  Cache blacklist unavailable: %v", err)
 	}
 
 	// Find most similar cached prompt
-	var bestMatch string
+	var bestMatch, bestKey string
 	bestSimilarity := 0.0
 
 	for _, key := range keys {
@@ -86,100 +227,130 @@ func (sc *SemanticCache) GetCachedResponse(ctx context.Context, tenantID int, pr
 
 		similarity := cosineSimilarity(queryEmbedding, cachedEmbedding)
 
-		if similarity > bestSimilarity && similarity >= sc.similarityThreshold {
+		if similarity > bestSimilarity && similarity >= threshold {
+			// Extract prompt hash from key: "embedding:tenant:1:prompt:abc123" or "embedding:namespace:2:prompt:abc123"
+			candidate := key[strings.LastIndex(key, ":")+1:]
+			if blacklisted[candidate] {
+				continue
+			}
 			bestSimilarity = similarity
-			// Extract prompt hash from key: "embedding:tenant:1:prompt:abc123"
-			bestMatch = key[len(fmt.Sprintf("embedding:tenant:%d:prompt:", tenantID)):]
+			bestMatch = candidate
+			bestKey = key
 		}
 	}
 
 	// If we found a similar prompt, get its response
 	if bestMatch != "" {
-		cached, err := sc.db.GetCachedResponse(ctx, tenantID, bestMatch)
+		var namespaceID int
+		fmt.Sscanf(bestKey, "embedding:namespace:%d:", &namespaceID)
+
+		var hit *Hit
+		if namespaceID != 0 {
+			hit, err = sc.getNamespaceResponse(ctx, tenantID, []int{namespaceID}, bestMatch, opts, bestSimilarity, true)
+		} else {
+			var cached *models.SemanticCache
+			if cached, err = sc.cachedEntry(ctx, tenantID, bestMatch, opts.MaxAge); err == nil {
+				hit, err = sc.newHit(ctx, tenantID, cached, bestSimilarity, true)
+			}
+		}
 		if err == nil {
-			return cached.Response, true, nil
+			sc.recordHit(tenantID, promptHash, hit)
+			return hit, true, nil
+		}
+		if errors.Is(err, pgx.ErrNoRows) && opts.MaxAge == 0 {
+			// The row expired or was evicted; drop its orphaned embedding
+			sc.redis.Del(ctx, bestKey)
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, err
+		}
+	}
+
+	return nil, false, nil
+}
+
+// cachedEntry reads a tenant's own live entry for promptHash. An expired one
+// is deleted on the way, along with its embedding.
+func (sc *SemanticCache) cachedEntry(ctx context.Context, tenantID int, promptHash string, maxAge time.Duration) (*models.SemanticCache, error) {
+	cached, err := sc.db.GetCachedResponse(ctx, tenantID, promptHash, maxAge)
+	if errors.Is(err, pgx.ErrNoRows) {
+		expired, expireErr := sc.db.ExpireCachedResponse(ctx, tenantID, promptHash)
+		if expireErr != nil {
+			log.Printf("⚠️  Failed to delete expired cache entry: %v", expireErr)
 		}
+		sc.deleteEmbeddings(ctx, expired)
+	}
+	return cached, err
+}
+
+// newHit decrypts a cached entry and wraps it, flagging it stale once it
+// outlives the soft TTL.
+func (sc *SemanticCache) newHit(ctx context.Context, tenantID int, entry *models.SemanticCache, similarity float64, semantic bool) (*Hit, error) {
+	if err := sc.open(ctx, entry); err != nil {
+		return nil, err
 	}
 
-	return "", false, nil
+	hit := &Hit{Entry: entry, Similarity: similarity, Semantic: semantic}
+	if softTTL := sc.policyFor(ctx, tenantID).SoftTTL; softTTL > 0 && hit.Age() > softTTL {
+		hit.Stale = true
+	}
+	return hit, nil
 }
 
-func (sc *SemanticCache) StoreCachedResponse(ctx context.Context, tenantID int, prompt, response string) error {
-	promptHash := sc.hashPrompt(prompt)
+func (sc *SemanticCache) StoreCachedResponse(ctx context.Context, tenantID int, model, prompt string, response *Response) error {
+	normalized := sc.Normalize(ctx, tenantID, prompt)
+	promptHash := hashNormalized(model, normalized)
 
 	// Store in PostgreSQL
 	cache := &models.SemanticCache{
-		TenantID:        tenantID,
-		PromptHash:      promptHash,
-		Prompt:          prompt,
-		Response:        response,
-		EmbeddingStored: false,
+		TenantID:         tenantID,
+		PromptHash:       promptHash,
+		Model:            model,
+		Prompt:           prompt,
+		NormalizedPrompt: normalized,
+		Response:         string(response.Body),
+		StatusCode:       response.StatusCode,
+		ResponseHeaders:  response.Header,
+		ContentEncoding:  response.ContentEncoding,
+		EmbeddingStored:  false,
 	}
 
-	err := sc.db.StoreCachedResponse(ctx, cache)
+	policy := sc.policyFor(ctx, tenantID)
+
+	if err := sc.seal(ctx, cache); err != nil {
+		return err
+	}
+	err := sc.db.StoreCachedResponse(ctx, cache, policy.TTL)
 	if err != nil {
 		return err
 	}
 
-	// Store embedding asynchronously
+	// Store embedding, enforce the size limit and share the entry asynchronously
 	go func() {
 		bgCtx := context.Background()
 
-		embedding, err := sc.getEmbedding(prompt)
-		if err != nil {
-			return
-		}
+		sc.evict(bgCtx, tenantID, policy)
+
+		var embeddingJSON []byte
+		if vector, err := sc.getEmbedding(bgCtx, normalized); err == nil {
+			embeddingJSON, _ = json.Marshal(vector)
 
-		embeddingKey := fmt.Sprintf("embedding:tenant:%d:prompt:%s", tenantID, promptHash)
-		embeddingJSON, _ := json.Marshal(embedding)
+			// Embeddings expire together with their row
+			sc.redis.Set(bgCtx, embeddingKey(tenantID, model, promptHash), embeddingJSON, policy.TTL)
+		}
 
-		// Store with 7-day expiration
-		sc.redis.Set(bgCtx, embeddingKey, embeddingJSON, 7*24*60*60)
+		sc.publish(bgCtx, tenantID, *cache, embeddingJSON, policy)
 	}()
 
 	return nil
 }
 
-func (sc *SemanticCache) getEmbedding(text string) ([]float64, error) {
-	reqBody, _ := json.Marshal(map[string]string{"text": text})
-
-	// Add timeout for embedding service
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
-
-	resp, err := client.Post(
-		sc.embeddingService+"/embed",
-		"application/json",
-		bytes.NewBuffer(reqBody),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("embedding service request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("embedding service returned status %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read embedding response: %w", err)
-	}
-
-	var result struct {
-		Embedding []float64 `json:"embedding"`
-	}
-
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse embedding response: %w", err)
-	}
-
-	if len(result.Embedding) == 0 {
-		return nil, fmt.Errorf("empty embedding returned")
-	}
+// EmbedderInfo describes the embedding provider in use, for cache statistics.
+func (sc *SemanticCache) EmbedderInfo() map[string]interface{} {
+	return embedding.Describe(sc.embedder)
+}
 
-	return result.Embedding, nil
+func (sc *SemanticCache) getEmbedding(ctx context.Context, text string) ([]float64, error) {
+	return embedding.EmbedOne(ctx, sc.embedder, text)
 }
 
 // Calculate cosine similarity between two vectors
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+)
+
+// How long tenant settings are reused before being re-read from Postgres
+const settingsTTL = 30 * time.Second
+
+type cachedSettings struct {
+	settings *models.TenantCacheSettings
+	loadedAt time.Time
+}
+
+// settingsStore is a short-lived in-memory copy of tenant_cache_settings, so
+// a request doesn't read the same row several times.
+type settingsStore struct {
+	mu      sync.Mutex
+	entries map[int]cachedSettings
+}
+
+// settingsFor returns a tenant's cache settings. Tenants without a row (or
+// when it can't be read) get empty settings, i.e. all defaults.
+func (sc *SemanticCache) settingsFor(ctx context.Context, tenantID int) *models.TenantCacheSettings {
+	sc.settings.mu.Lock()
+	cached, ok := sc.settings.entries[tenantID]
+	sc.settings.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < settingsTTL {
+		return cached.settings
+	}
+
+	settings, err := sc.db.GetTenantCacheSettings(ctx, tenantID)
+	if err != nil {
+		settings = &models.TenantCacheSettings{TenantID: tenantID}
+	}
+
+	sc.settings.mu.Lock()
+	sc.settings.entries[tenantID] = cachedSettings{settings: settings, loadedAt: time.Now()}
+	sc.settings.mu.Unlock()
+
+	return settings
+}
+
+// ForgetSettings drops the in-memory copy of a tenant's settings after they change.
+func (sc *SemanticCache) ForgetSettings(tenantID int) {
+	sc.settings.mu.Lock()
+	delete(sc.settings.entries, tenantID)
+	sc.settings.mu.Unlock()
+}
+
+// policyFor merges a tenant's cache settings over the gateway defaults.
+func (sc *SemanticCache) policyFor(ctx context.Context, tenantID int) Policy {
+	policy := sc.defaults
+	settings := sc.settingsFor(ctx, tenantID)
+
+	if settings.TTLSeconds != nil {
+		policy.TTL = time.Duration(*settings.TTLSeconds) * time.Second
+	}
+	if settings.SoftTTLSeconds != nil {
+		policy.SoftTTL = time.Duration(*settings.SoftTTLSeconds) * time.Second
+	}
+	if settings.EncryptAtRest != nil {
+		policy.EncryptAtRest = *settings.EncryptAtRest
+	}
+	if settings.SharedCache != nil {
+		policy.SharedCache = *settings.SharedCache
+	}
+	if settings.SimilarityThreshold != nil {
+		policy.SimilarityThreshold = *settings.SimilarityThreshold
+	}
+	if settings.MaxEntries != nil {
+		policy.MaxEntries = *settings.MaxEntries
+	}
+	if settings.EvictionPolicy != nil {
+		policy.EvictionPolicy = *settings.EvictionPolicy
+	}
+
+	return policy
+}
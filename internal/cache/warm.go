@@ -0,0 +1,285 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// ImportEntry is one line of a cache import file. The response is either the
+// exact body to return (a JSON string or object), or a plain-text answer that
+// is wrapped in an OpenAI chat completion. Lines written by Export can be
+// imported as they are, headers included.
+type ImportEntry struct {
+	Prompt string `json:"prompt"`
+	Model  string `json:"model"` // the model requests must ask for to be answered by it
+
+	Response        json.RawMessage   `json:"response"`
+	Answer          string            `json:"answer"`
+	StatusCode      int               `json:"status_code"`
+	ResponseHeaders map[string]string `json:"response_headers"`
+}
+
+// ImportOptions controls an import.
+type ImportOptions struct {
+	DryRun    bool // validate and count only, write nothing
+	Overwrite bool // replace entries that are already cached
+	BatchSize int  // entries embedded and written per round trip
+}
+
+// ImportReport summarizes an import.
+type ImportReport struct {
+	DryRun     bool          `json:"dry_run"`
+	Lines      int           `json:"lines"`
+	Imported   int           `json:"imported"`
+	Duplicates int           `json:"duplicates"` // repeated within the file
+	Existing   int           `json:"existing"`   // already cached and not overwritten
+	Invalid    int           `json:"invalid"`
+	Embedded   int           `json:"embedded"`
+	Errors     []ImportError `json:"errors,omitempty"`
+}
+
+// ImportError points at a line that couldn't be imported.
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Only the first errors are reported, the rest are just counted
+const maxImportErrors = 100
+
+// Import loads JSONL prompt/response pairs into a tenant's cache. Prompts are
+// deduplicated by their normalized hash, and embeddings are computed in batches.
+func (sc *SemanticCache) Import(ctx context.Context, tenantID int, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+
+	report := &ImportReport{DryRun: opts.DryRun}
+	policy := sc.policyFor(ctx, tenantID)
+	seen := make(map[string]bool)
+	var batch []*models.SemanticCache
+
+	fail := func(line int, err error) {
+		report.Invalid++
+		if len(report.Errors) < maxImportErrors {
+			report.Errors = append(report.Errors, ImportError{Line: line, Error: err.Error()})
+		}
+	}
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		defer func() { batch = batch[:0] }()
+
+		if !opts.Overwrite {
+			hashes := make([]string, len(batch))
+			for i, entry := range batch {
+				hashes[i] = entry.PromptHash
+			}
+			existing, err := sc.db.ExistingCacheHashes(ctx, tenantID, hashes)
+			if err != nil {
+				return err
+			}
+
+			fresh := batch[:0]
+			for _, entry := range batch {
+				if existing[entry.PromptHash] {
+					report.Existing++
+				} else {
+					fresh = append(fresh, entry)
+				}
+			}
+			batch = fresh
+		}
+
+		if opts.DryRun || len(batch) == 0 {
+			report.Imported += len(batch)
+			return nil
+		}
+
+		embedded, err := sc.importBatch(ctx, tenantID, batch, policy)
+		if err != nil {
+			return err
+		}
+		report.Imported += len(batch)
+		report.Embedded += embedded
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		report.Lines++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var in ImportEntry
+		if err := json.Unmarshal([]byte(line), &in); err != nil {
+			fail(report.Lines, fmt.Errorf("invalid JSON: %w", err))
+			continue
+		}
+		response, err := in.body()
+		if err != nil {
+			fail(report.Lines, err)
+			continue
+		}
+		if strings.TrimSpace(in.Prompt) == "" {
+			fail(report.Lines, fmt.Errorf("prompt is required"))
+			continue
+		}
+		if in.StatusCode != 0 && (in.StatusCode < 100 || in.StatusCode > 599) {
+			fail(report.Lines, fmt.Errorf("invalid status_code %d", in.StatusCode))
+			continue
+		}
+
+		normalized := sc.Normalize(ctx, tenantID, in.Prompt)
+		hash := hashNormalized(in.Model, normalized)
+		if seen[hash] {
+			report.Duplicates++
+			continue
+		}
+		seen[hash] = true
+
+		batch = append(batch, &models.SemanticCache{
+			TenantID:         tenantID,
+			PromptHash:       hash,
+			Model:            in.Model,
+			Prompt:           in.Prompt,
+			NormalizedPrompt: normalized,
+			Response:         response,
+			StatusCode:       in.StatusCode,
+			ResponseHeaders:  in.ResponseHeaders,
+		})
+		if len(batch) >= opts.BatchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return report, err
+	}
+	if err := flush(); err != nil {
+		return report, err
+	}
+
+	if !opts.DryRun {
+		sc.evict(ctx, tenantID, policy)
+	}
+
+	return report, nil
+}
+
+// body returns the response to cache for an import line
+func (in *ImportEntry) body() (string, error) {
+	switch {
+	case len(in.Response) > 0 && string(in.Response) != "null":
+		var s string
+		if err := json.Unmarshal(in.Response, &s); err == nil {
+			if s == "" {
+				return "", fmt.Errorf("response is empty")
+			}
+			return s, nil
+		}
+		return string(in.Response), nil
+	case in.Answer != "":
+		completion, _ := json.Marshal(map[string]interface{}{
+			"id":      "cache-import",
+			"object":  "chat.completion",
+			"created": time.Now().Unix(),
+			"model":   "cache-import",
+			"choices": []interface{}{map[string]interface{}{
+				"index":         0,
+				"message":       map[string]string{"role": "assistant", "content": in.Answer},
+				"finish_reason": "stop",
+			}},
+		})
+		return string(completion), nil
+	default:
+		return "", fmt.Errorf("response or answer is required")
+	}
+}
+
+// importBatch writes a batch of entries and their embeddings. Embedding
+// failures don't fail the import, those entries just only match exactly.
+func (sc *SemanticCache) importBatch(ctx context.Context, tenantID int, batch []*models.SemanticCache, policy Policy) (int, error) {
+	texts := make([]string, len(batch))
+	for i, entry := range batch {
+		texts[i] = entry.NormalizedPrompt
+	}
+
+	vectors, err := sc.embedder.Embed(ctx, texts)
+	if err != nil {
+		log.Printf("⚠️  Import: embedding %d prompts failed: %v", len(texts), err)
+		vectors = nil
+	}
+	for _, entry := range batch {
+		entry.EmbeddingStored = vectors != nil
+		if err := sc.seal(ctx, entry); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := sc.db.StoreCachedResponses(ctx, batch, policy.TTL); err != nil {
+		return 0, err
+	}
+
+	if vectors == nil {
+		return 0, nil
+	}
+
+	_, err = sc.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, entry := range batch {
+			embeddingJSON, _ := json.Marshal(vectors[i])
+			pipe.Set(ctx, embeddingKey(tenantID, entry.Model, entry.PromptHash), embeddingJSON, policy.TTL)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("⚠️  Import: storing %d embeddings failed: %v", len(batch), err)
+		return 0, nil
+	}
+
+	return len(batch), nil
+}
+
+// Export writes a tenant's cache as JSONL, one entry per line including its
+// hit statistics, and returns the number of entries written.
+func (sc *SemanticCache) Export(ctx context.Context, tenantID int, w io.Writer) (int, error) {
+	enc := json.NewEncoder(w)
+	var afterID int64
+	count := 0
+
+	for {
+		entries, err := sc.db.ListCacheEntriesAfter(ctx, tenantID, afterID, 500)
+		if err != nil {
+			return count, err
+		}
+		if len(entries) == 0 {
+			return count, nil
+		}
+
+		for i := range entries {
+			if err := sc.open(ctx, &entries[i]); err != nil {
+				return count, err
+			}
+			if err := enc.Encode(&entries[i]); err != nil {
+				return count, err
+			}
+			count++
+		}
+		afterID = entries[len(entries)-1].ID
+	}
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +14,86 @@ type Config struct {
 	RedisURL    string
 	JWTSecret   string
 	ServerPort  string
+
+	// Semantic cache
+	CacheTTL             time.Duration
+	CacheSoftTTL         time.Duration
+	CacheMaxEntries      int
+	CacheEvictionPolicy  string
+	CacheJanitorInterval time.Duration
+	CacheNormalizers     []string
+
+	// Semantic matching and feedback on bad hits
+	CacheSimilarityThreshold    float64
+	CacheFeedbackBlacklistAfter int
+	CacheFeedbackRaiseAfter     int
+	CacheMaxSimilarityThreshold float64
+
+	// Per-model prices used to estimate what cache hits saved
+	ModelPrices string
+
+	// Cache storage at rest
+	CacheEncryptAtRest   bool
+	CacheCompressMinSize int
+	KMSProvider          string
+	KMSMasterKey         string
+	KMSKeyringPath       string
+	CredentialCacheTTL   time.Duration
+
+	// Embedding provider
+	EmbeddingProvider  string
+	EmbeddingURL       string
+	EmbeddingModel     string
+	EmbeddingAPIKey    string
+	EmbeddingTimeout   time.Duration
+	EmbeddingBatchSize int
+	EmbeddingBatchWait time.Duration
+	EmbeddingCacheSize int
+	EmbeddingDims      int
+
+	// Request coalescing
+	CoalesceRequests  bool
+	CoalesceRedisLock bool
+	CoalesceLockTTL   time.Duration
+
+	// Replaying cached answers to streaming clients
+	StreamChunkSize int
+	StreamPacing    time.Duration
+
+	// Connections to tenant backends
+	RouteCacheTTL                 time.Duration
+	UpstreamDialTimeout           time.Duration
+	UpstreamTLSHandshakeTimeout   time.Duration
+	UpstreamResponseHeaderTimeout time.Duration
+	UpstreamIdleConnTimeout       time.Duration
+	UpstreamMaxIdleConnsPerHost   int
+	UpstreamMaxConnsPerHost       int
+	UpstreamHTTP2                 bool
+
+	// Retrying failed backend calls
+	RetryMax         int
+	RetryBaseDelay   time.Duration
+	RetryMaxDelay    time.Duration
+	RetryBudgetRatio float64
+	RetryBudgetBurst float64
+
+	// Backend health checking
+	HealthCheckInterval       time.Duration
+	HealthCheckTimeout        time.Duration
+	HealthCheckExpectedStatus int
+	HealthCheckFailures       int
+	OutlierFailures           int
+	OutlierBaseEjection       time.Duration
+	OutlierMaxEjection        time.Duration
+
+	// Circuit breaking per backend
+	CircuitWindow           time.Duration
+	CircuitMinRequests      int
+	CircuitFailureRate      float64
+	CircuitSlowCallDuration time.Duration
+	CircuitSlowCallRate     float64
+	CircuitOpenDuration     time.Duration
+	CircuitHalfOpenCalls    int
 }
 
 func Load() (*Config, error) {
@@ -21,6 +104,75 @@ func Load() (*Config, error) {
 		RedisURL:    getEnv("REDIS_URL", "redis://localhost:6379"),
 		JWTSecret:   getEnv("JWT_SECRET", "secret"),
 		ServerPort:  getEnv("SERVER_PORT", "8080"),
+
+		CacheTTL:             getEnvDuration("CACHE_TTL", 7*24*time.Hour),
+		CacheSoftTTL:         getEnvDuration("CACHE_SOFT_TTL", 0),
+		CacheMaxEntries:      getEnvInt("CACHE_MAX_ENTRIES", 0),
+		CacheEvictionPolicy:  getEnv("CACHE_EVICTION_POLICY", "lru"),
+		CacheJanitorInterval: getEnvDuration("CACHE_JANITOR_INTERVAL", 5*time.Minute),
+		CacheNormalizers:     getEnvList("CACHE_NORMALIZERS", nil),
+
+		CacheSimilarityThreshold:    getEnvFloat("CACHE_SIMILARITY_THRESHOLD", 0.85),
+		CacheFeedbackBlacklistAfter: getEnvInt("CACHE_FEEDBACK_BLACKLIST_AFTER", 1),
+		CacheFeedbackRaiseAfter:     getEnvInt("CACHE_FEEDBACK_RAISE_AFTER", 5),
+		CacheMaxSimilarityThreshold: getEnvFloat("CACHE_MAX_SIMILARITY_THRESHOLD", 0.98),
+
+		ModelPrices: getEnv("MODEL_PRICES", ""),
+
+		CacheEncryptAtRest:   getEnvBool("CACHE_ENCRYPT_AT_REST", false),
+		CacheCompressMinSize: getEnvInt("CACHE_COMPRESS_MIN_SIZE", 4096),
+		KMSProvider:          getEnv("KMS_PROVIDER", ""),
+		KMSMasterKey:         getEnv("KMS_MASTER_KEY", ""),
+		KMSKeyringPath:       getEnv("KMS_KEYRING_PATH", "kms-keyring.json"),
+		CredentialCacheTTL:   getEnvDuration("CREDENTIAL_CACHE_TTL", time.Minute),
+
+		EmbeddingProvider:  getEnv("EMBEDDING_PROVIDER", "flask"),
+		EmbeddingURL:       getEnv("EMBEDDING_URL", "http://localhost:5000"),
+		EmbeddingModel:     getEnv("EMBEDDING_MODEL", ""),
+		EmbeddingAPIKey:    getEnv("EMBEDDING_API_KEY", ""),
+		EmbeddingTimeout:   getEnvDuration("EMBEDDING_TIMEOUT", 5*time.Second),
+		EmbeddingBatchSize: getEnvInt("EMBEDDING_BATCH_SIZE", 32),
+		EmbeddingBatchWait: getEnvDuration("EMBEDDING_BATCH_WAIT", 5*time.Millisecond),
+		EmbeddingCacheSize: getEnvInt("EMBEDDING_CACHE_SIZE", 10000),
+		EmbeddingDims:      getEnvInt("EMBEDDING_DIMENSIONS", 512),
+
+		CoalesceRequests:  getEnvBool("COALESCE_REQUESTS", false),
+		CoalesceRedisLock: getEnvBool("COALESCE_REDIS_LOCK", false),
+		CoalesceLockTTL:   getEnvDuration("COALESCE_LOCK_TTL", 60*time.Second),
+
+		StreamChunkSize: getEnvInt("CACHE_STREAM_CHUNK_SIZE", 20),
+		StreamPacing:    getEnvDuration("CACHE_STREAM_PACING", 0),
+
+		RouteCacheTTL:                 getEnvDuration("ROUTE_CACHE_TTL", 30*time.Second),
+		UpstreamDialTimeout:           getEnvDuration("UPSTREAM_DIAL_TIMEOUT", 10*time.Second),
+		UpstreamTLSHandshakeTimeout:   getEnvDuration("UPSTREAM_TLS_HANDSHAKE_TIMEOUT", 10*time.Second),
+		UpstreamResponseHeaderTimeout: getEnvDuration("UPSTREAM_RESPONSE_HEADER_TIMEOUT", 0),
+		UpstreamIdleConnTimeout:       getEnvDuration("UPSTREAM_IDLE_CONN_TIMEOUT", 90*time.Second),
+		UpstreamMaxIdleConnsPerHost:   getEnvInt("UPSTREAM_MAX_IDLE_CONNS_PER_HOST", 32),
+		UpstreamMaxConnsPerHost:       getEnvInt("UPSTREAM_MAX_CONNS_PER_HOST", 0),
+		UpstreamHTTP2:                 getEnvBool("UPSTREAM_HTTP2", true),
+
+		RetryMax:         getEnvInt("RETRY_MAX", 2),
+		RetryBaseDelay:   getEnvDuration("RETRY_BASE_DELAY", 200*time.Millisecond),
+		RetryMaxDelay:    getEnvDuration("RETRY_MAX_DELAY", 5*time.Second),
+		RetryBudgetRatio: getEnvFloat("RETRY_BUDGET_RATIO", 0.2),
+		RetryBudgetBurst: getEnvFloat("RETRY_BUDGET_BURST", 10),
+
+		HealthCheckInterval:       getEnvDuration("HEALTH_CHECK_INTERVAL", 10*time.Second),
+		HealthCheckTimeout:        getEnvDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		HealthCheckExpectedStatus: getEnvInt("HEALTH_CHECK_EXPECTED_STATUS", 200),
+		HealthCheckFailures:       getEnvInt("HEALTH_CHECK_FAILURES", 2),
+		OutlierFailures:           getEnvInt("OUTLIER_CONSECUTIVE_FAILURES", 5),
+		OutlierBaseEjection:       getEnvDuration("OUTLIER_BASE_EJECTION", 30*time.Second),
+		OutlierMaxEjection:        getEnvDuration("OUTLIER_MAX_EJECTION", 5*time.Minute),
+
+		CircuitWindow:           getEnvDuration("CIRCUIT_WINDOW", 60*time.Second),
+		CircuitMinRequests:      getEnvInt("CIRCUIT_MIN_REQUESTS", 10),
+		CircuitFailureRate:      getEnvFloat("CIRCUIT_FAILURE_RATE", 0.5),
+		CircuitSlowCallDuration: getEnvDuration("CIRCUIT_SLOW_CALL_DURATION", 30*time.Second),
+		CircuitSlowCallRate:     getEnvFloat("CIRCUIT_SLOW_CALL_RATE", 0.8),
+		CircuitOpenDuration:     getEnvDuration("CIRCUIT_OPEN_DURATION", 30*time.Second),
+		CircuitHalfOpenCalls:    getEnvInt("CIRCUIT_HALF_OPEN_CALLS", 3),
 	}, nil
 }
 
@@ -30,3 +182,43 @@ func getEnv(key, defaultVal string) string {
 	}
 	return defaultVal
 }
+
+func getEnvInt(key string, defaultVal int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultVal
+}
+
+func getEnvFloat(key string, defaultVal float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return defaultVal
+}
+
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultVal
+}
+
+func getEnvBool(key string, defaultVal bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultVal
+}
+
+// getEnvList reads a comma-separated list. Set the variable to "none" for an empty list.
+func getEnvList(key string, defaultVal []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultVal
+	}
+	if value == "none" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
@@ -0,0 +1,329 @@
+package db
+
+import (
+	"context"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// ============ Tenant Backend Pools ============
+
+const tenantBackendColumns = `id, tenant_id, url, weight, enabled, COALESCE(health_check_path, ''), created_at, updated_at`
+
+func scanTenantBackend(row pgx.Row) (*models.TenantBackend, error) {
+	var backend models.TenantBackend
+	err := row.Scan(
+		&backend.ID,
+		&backend.TenantID,
+		&backend.URL,
+		&backend.Weight,
+		&backend.Enabled,
+		&backend.HealthCheckPath,
+		&backend.CreatedAt,
+		&backend.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &backend, nil
+}
+
+// ListTenantBackends returns a tenant's pool, optionally only the enabled backends.
+func (db *DB) ListTenantBackends(ctx context.Context, tenantID int, enabledOnly bool) ([]models.TenantBackend, error) {
+	query := `
+        SELECT ` + tenantBackendColumns + `
+        FROM tenant_backends
+        WHERE tenant_id = $1 AND (enabled OR NOT $2)
+        ORDER BY id
+    `
+
+	rows, err := db.Pool.Query(ctx, query, tenantID, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	backends := []models.TenantBackend{}
+	for rows.Next() {
+		backend, err := scanTenantBackend(rows)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, *backend)
+	}
+
+	return backends, rows.Err()
+}
+
+func (db *DB) CreateTenantBackend(ctx context.Context, backend *models.TenantBackend) error {
+	query := `
+        INSERT INTO tenant_backends (tenant_id, url, weight, enabled, health_check_path)
+        VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+        RETURNING id, created_at, updated_at
+    `
+
+	return db.Pool.QueryRow(ctx, query,
+		backend.TenantID,
+		backend.URL,
+		backend.Weight,
+		backend.Enabled,
+		backend.HealthCheckPath,
+	).Scan(&backend.ID, &backend.CreatedAt, &backend.UpdatedAt)
+}
+
+func (db *DB) UpdateTenantBackend(ctx context.Context, backend *models.TenantBackend) error {
+	query := `
+        UPDATE tenant_backends
+        SET url = $3, weight = $4, enabled = $5, health_check_path = NULLIF($6, ''), updated_at = NOW()
+        WHERE tenant_id = $1 AND id = $2
+        RETURNING created_at, updated_at
+    `
+
+	return db.Pool.QueryRow(ctx, query,
+		backend.TenantID,
+		backend.ID,
+		backend.URL,
+		backend.Weight,
+		backend.Enabled,
+		backend.HealthCheckPath,
+	).Scan(&backend.CreatedAt, &backend.UpdatedAt)
+}
+
+func (db *DB) GetTenantBackend(ctx context.Context, tenantID, id int) (*models.TenantBackend, error) {
+	query := `SELECT ` + tenantBackendColumns + ` FROM tenant_backends WHERE tenant_id = $1 AND id = $2`
+	return scanTenantBackend(db.Pool.QueryRow(ctx, query, tenantID, id))
+}
+
+func (db *DB) DeleteTenantBackend(ctx context.Context, tenantID, id int) error {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM tenant_backends WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// ListHealthCheckTargets returns every enabled backend URL that has a health
+// check path, once per URL. A URL shared by several tenants is probed on the
+// path of the backend added first.
+func (db *DB) ListHealthCheckTargets(ctx context.Context) ([]models.TenantBackend, error) {
+	query := `
+        SELECT DISTINCT ON (url) ` + tenantBackendColumns + `
+        FROM tenant_backends
+        WHERE enabled AND health_check_path IS NOT NULL
+        ORDER BY url, id
+    `
+
+	rows, err := db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	backends := []models.TenantBackend{}
+	for rows.Next() {
+		backend, err := scanTenantBackend(rows)
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, *backend)
+	}
+
+	return backends, rows.Err()
+}
+
+func (db *DB) RecordCircuitBreakerEvent(ctx context.Context, event *models.CircuitBreakerEvent) error {
+	query := `
+        INSERT INTO circuit_breaker_events (backend, from_state, to_state, reason, instance, created_at)
+        VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+        RETURNING id
+    `
+
+	return db.Pool.QueryRow(ctx, query,
+		event.Backend,
+		event.FromState,
+		event.ToState,
+		event.Reason,
+		event.Instance,
+		event.CreatedAt,
+	).Scan(&event.ID)
+}
+
+// ListCircuitBreakerEvents returns the latest transitions, newest first,
+// optionally of one backend only.
+func (db *DB) ListCircuitBreakerEvents(ctx context.Context, backend string, limit int) ([]models.CircuitBreakerEvent, error) {
+	query := `
+        SELECT id, backend, from_state, to_state, reason, COALESCE(instance, ''), created_at
+        FROM circuit_breaker_events
+        WHERE $1 = '' OR backend = $1
+        ORDER BY created_at DESC, id DESC
+        LIMIT $2
+    `
+
+	rows, err := db.Pool.Query(ctx, query, backend, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.CircuitBreakerEvent{}
+	for rows.Next() {
+		var event models.CircuitBreakerEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.Backend,
+			&event.FromState,
+			&event.ToState,
+			&event.Reason,
+			&event.Instance,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// ============ Tenant Fallback Chains ============
+
+// ListTenantFallbacks returns a tenant's fallback chain in order, optionally
+// only the enabled steps.
+func (db *DB) ListTenantFallbacks(ctx context.Context, tenantID int, enabledOnly bool) ([]models.TenantFallback, error) {
+	query := `
+        SELECT id, tenant_id, position, provider, url, COALESCE(model, ''), api_format, timeout_ms, enabled, created_at
+        FROM tenant_fallbacks
+        WHERE tenant_id = $1 AND (enabled OR NOT $2)
+        ORDER BY position
+    `
+
+	rows, err := db.Pool.Query(ctx, query, tenantID, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fallbacks := []models.TenantFallback{}
+	for rows.Next() {
+		var f models.TenantFallback
+		if err := rows.Scan(
+			&f.ID,
+			&f.TenantID,
+			&f.Position,
+			&f.Provider,
+			&f.URL,
+			&f.Model,
+			&f.APIFormat,
+			&f.TimeoutMs,
+			&f.Enabled,
+			&f.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		fallbacks = append(fallbacks, f)
+	}
+
+	return fallbacks, rows.Err()
+}
+
+// ReplaceTenantFallbacks swaps a tenant's whole chain for the given steps,
+// numbering them in order from 1.
+func (db *DB) ReplaceTenantFallbacks(ctx context.Context, tenantID int, fallbacks []models.TenantFallback) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM tenant_fallbacks WHERE tenant_id = $1`, tenantID); err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO tenant_fallbacks (tenant_id, position, provider, url, model, api_format, timeout_ms, enabled)
+        VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8)
+        RETURNING id, created_at
+    `
+	for i := range fallbacks {
+		f := &fallbacks[i]
+		f.TenantID = tenantID
+		f.Position = i + 1
+		if err := tx.QueryRow(ctx, query,
+			f.TenantID,
+			f.Position,
+			f.Provider,
+			f.URL,
+			f.Model,
+			f.APIFormat,
+			f.TimeoutMs,
+			f.Enabled,
+		).Scan(&f.ID, &f.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ============ Tenant Model Policies ============
+
+func (db *DB) GetTenantModelPolicy(ctx context.Context, tenantID int) (*models.TenantModelPolicy, error) {
+	query := `
+        SELECT tenant_id, allowed_models, denied_models, aliases, routes, updated_at
+        FROM tenant_model_policies
+        WHERE tenant_id = $1
+    `
+
+	var policy models.TenantModelPolicy
+	err := db.Pool.QueryRow(ctx, query, tenantID).Scan(
+		&policy.TenantID,
+		&policy.AllowedModels,
+		&policy.DeniedModels,
+		&policy.Aliases,
+		&policy.Routes,
+		&policy.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+// PutTenantModelPolicy replaces a tenant's model policy.
+func (db *DB) PutTenantModelPolicy(ctx context.Context, policy *models.TenantModelPolicy) error {
+	query := `
+        INSERT INTO tenant_model_policies (tenant_id, allowed_models, denied_models, aliases, routes)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (tenant_id) DO UPDATE
+        SET allowed_models = EXCLUDED.allowed_models,
+            denied_models = EXCLUDED.denied_models,
+            aliases = EXCLUDED.aliases,
+            routes = EXCLUDED.routes,
+            updated_at = NOW()
+        RETURNING updated_at
+    `
+
+	return db.Pool.QueryRow(ctx, query,
+		policy.TenantID,
+		policy.AllowedModels,
+		policy.DeniedModels,
+		policy.Aliases,
+		policy.Routes,
+	).Scan(&policy.UpdatedAt)
+}
+
+func (db *DB) DeleteTenantModelPolicy(ctx context.Context, tenantID int) error {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM tenant_model_policies WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,525 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// ============ Cache Maintenance ============
+
+// Row ordering for each eviction policy, most valuable entries first
+var evictionOrder = map[string]string{
+	"lru": "last_accessed DESC",
+	"lfu": "hit_count DESC, last_accessed DESC",
+}
+
+// Shared entries have no tenant, so refs carry a zero tenant ID for them
+const cacheEntryRefColumns = `COALESCE(tenant_id, 0), prompt_hash, model, COALESCE(namespace_id, 0)`
+
+// DeleteExpiredCacheEntries removes every expired row and returns what was deleted.
+func (db *DB) DeleteExpiredCacheEntries(ctx context.Context) ([]models.CacheEntryRef, error) {
+	query := `
+        DELETE FROM semantic_cache
+        WHERE expires_at <= NOW()
+        RETURNING ` + cacheEntryRefColumns + `
+    `
+
+	return db.queryCacheEntryRefs(ctx, query)
+}
+
+// ExpireCachedResponse deletes a tenant's entry for promptHash if it has
+// expired and returns what was deleted.
+func (db *DB) ExpireCachedResponse(ctx context.Context, tenantID int, promptHash string) ([]models.CacheEntryRef, error) {
+	query := `
+        DELETE FROM semantic_cache
+        WHERE tenant_id = $1 AND prompt_hash = $2 AND expires_at <= NOW()
+        RETURNING ` + cacheEntryRefColumns + `
+    `
+
+	return db.queryCacheEntryRefs(ctx, query, tenantID, promptHash)
+}
+
+// CountCacheEntriesByTenant returns the number of cache rows held by each tenant.
+func (db *DB) CountCacheEntriesByTenant(ctx context.Context) (map[int]int64, error) {
+	query := `
+        SELECT tenant_id, COUNT(*)
+        FROM semantic_cache
+        WHERE tenant_id IS NOT NULL
+        GROUP BY tenant_id
+    `
+
+	rows, err := db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int64)
+	for rows.Next() {
+		var tenantID int
+		var count int64
+		if err := rows.Scan(&tenantID, &count); err != nil {
+			return nil, err
+		}
+		counts[tenantID] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// EvictCacheEntries trims a tenant's cache down to maxEntries rows, dropping the
+// least valuable rows according to the eviction policy ("lru" or "lfu").
+func (db *DB) EvictCacheEntries(ctx context.Context, tenantID, maxEntries int, policy string) ([]models.CacheEntryRef, error) {
+	return db.evictEntries(ctx, "tenant_id = $1", tenantID, maxEntries, policy)
+}
+
+// EvictNamespaceEntries trims a shared namespace the way EvictCacheEntries trims a tenant.
+func (db *DB) EvictNamespaceEntries(ctx context.Context, namespaceID, maxEntries int, policy string) ([]models.CacheEntryRef, error) {
+	return db.evictEntries(ctx, "namespace_id = $1", namespaceID, maxEntries, policy)
+}
+
+func (db *DB) evictEntries(ctx context.Context, scope string, scopeID, maxEntries int, policy string) ([]models.CacheEntryRef, error) {
+	order, ok := evictionOrder[policy]
+	if !ok {
+		return nil, fmt.Errorf("unknown eviction policy %q", policy)
+	}
+
+	query := `
+        DELETE FROM semantic_cache
+        WHERE id IN (
+            SELECT id FROM semantic_cache
+            WHERE ` + scope + `
+            ORDER BY ` + order + `
+            OFFSET $2
+        )
+        RETURNING ` + cacheEntryRefColumns + `
+    `
+
+	return db.queryCacheEntryRefs(ctx, query, scopeID, maxEntries)
+}
+
+func (db *DB) queryCacheEntryRefs(ctx context.Context, query string, args ...interface{}) ([]models.CacheEntryRef, error) {
+	rows, err := db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []models.CacheEntryRef
+	for rows.Next() {
+		var ref models.CacheEntryRef
+		if err := rows.Scan(&ref.TenantID, &ref.PromptHash, &ref.Model, &ref.NamespaceID); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+
+	return refs, rows.Err()
+}
+
+// ============ Tenant Cache Settings ============
+
+func (db *DB) GetTenantCacheSettings(ctx context.Context, tenantID int) (*models.TenantCacheSettings, error) {
+	query := `
+        SELECT tenant_id, ttl_seconds, soft_ttl_seconds, max_entries, eviction_policy, encrypt_at_rest,
+               shared_cache, similarity_threshold, threshold_adjusted_at, normalization_rules, updated_at
+        FROM tenant_cache_settings
+        WHERE tenant_id = $1
+    `
+
+	var settings models.TenantCacheSettings
+	err := db.Pool.QueryRow(ctx, query, tenantID).Scan(
+		&settings.TenantID,
+		&settings.TTLSeconds,
+		&settings.SoftTTLSeconds,
+		&settings.MaxEntries,
+		&settings.EvictionPolicy,
+		&settings.EncryptAtRest,
+		&settings.SharedCache,
+		&settings.SimilarityThreshold,
+		&settings.ThresholdAdjustedAt,
+		&settings.NormalizationRules,
+		&settings.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// PutTenantCacheSettings replaces a tenant's cache settings.
+func (db *DB) PutTenantCacheSettings(ctx context.Context, settings *models.TenantCacheSettings) error {
+	query := `
+        INSERT INTO tenant_cache_settings (tenant_id, ttl_seconds, soft_ttl_seconds, max_entries, eviction_policy, encrypt_at_rest,
+            shared_cache, similarity_threshold, normalization_rules)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        ON CONFLICT (tenant_id) DO UPDATE
+        SET ttl_seconds = EXCLUDED.ttl_seconds,
+            soft_ttl_seconds = EXCLUDED.soft_ttl_seconds,
+            max_entries = EXCLUDED.max_entries,
+            eviction_policy = EXCLUDED.eviction_policy,
+            encrypt_at_rest = EXCLUDED.encrypt_at_rest,
+            shared_cache = EXCLUDED.shared_cache,
+            similarity_threshold = EXCLUDED.similarity_threshold,
+            normalization_rules = EXCLUDED.normalization_rules,
+            updated_at = NOW()
+        RETURNING updated_at, threshold_adjusted_at
+    `
+
+	return db.Pool.QueryRow(ctx, query,
+		settings.TenantID,
+		settings.TTLSeconds,
+		settings.SoftTTLSeconds,
+		settings.MaxEntries,
+		settings.EvictionPolicy,
+		settings.EncryptAtRest,
+		settings.SharedCache,
+		settings.SimilarityThreshold,
+		settings.NormalizationRules,
+	).Scan(&settings.UpdatedAt, &settings.ThresholdAdjustedAt)
+}
+
+// ============ Cache Entry Management ============
+
+const cacheEntryColumns = `id, COALESCE(tenant_id, 0), prompt_hash, model, prompt, COALESCE(normalized_prompt, prompt), response, status_code, response_headers, COALESCE(content_encoding, ''), embedding_stored, data_key_version, COALESCE(compression, ''), namespace_id, source_tenant_id, hit_count, created_at, last_accessed, expires_at`
+
+// CacheEntryFilter selects cache rows within a tenant. Empty fields are ignored,
+// so the zero value matches every row.
+type CacheEntryFilter struct {
+	ID         int64
+	IDs        []int64
+	PromptHash string
+	Prefix     string
+	// Regex is Go (RE2) syntax, which Postgres doesn't speak. Callers match
+	// it themselves and pass the IDs of the matching rows instead.
+	Regex string
+}
+
+func (f CacheEntryFilter) where(args []interface{}) (string, []interface{}) {
+	clause := "tenant_id = $1"
+	if f.ID != 0 {
+		args = append(args, f.ID)
+		clause += fmt.Sprintf(" AND id = $%d", len(args))
+	}
+	if len(f.IDs) > 0 {
+		args = append(args, f.IDs)
+		clause += fmt.Sprintf(" AND id = ANY($%d)", len(args))
+	}
+	if f.PromptHash != "" {
+		args = append(args, f.PromptHash)
+		clause += fmt.Sprintf(" AND prompt_hash = $%d", len(args))
+	}
+	if f.Prefix != "" {
+		args = append(args, escapeLike(f.Prefix))
+		clause += fmt.Sprintf(" AND prompt LIKE $%d || '%%'", len(args))
+	}
+	return clause, args
+}
+
+// escapeLike makes s match literally inside a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func scanCacheEntry(row pgx.Row) (*models.SemanticCache, error) {
+	var cache models.SemanticCache
+	err := row.Scan(
+		&cache.ID,
+		&cache.TenantID,
+		&cache.PromptHash,
+		&cache.Model,
+		&cache.Prompt,
+		&cache.NormalizedPrompt,
+		&cache.Response,
+		&cache.StatusCode,
+		&cache.ResponseHeaders,
+		&cache.ContentEncoding,
+		&cache.EmbeddingStored,
+		&cache.DataKeyVersion,
+		&cache.Compression,
+		&cache.NamespaceID,
+		&cache.SourceTenantID,
+		&cache.HitCount,
+		&cache.CreatedAt,
+		&cache.LastAccessed,
+		&cache.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &cache, nil
+}
+
+// ListCacheEntries pages through a tenant's cache, newest first. A non-empty
+// search restricts the result to prompts containing it (case-insensitive).
+func (db *DB) ListCacheEntries(ctx context.Context, tenantID int, search string, limit, offset int) ([]models.SemanticCache, int64, error) {
+	where := "tenant_id = $1"
+	args := []interface{}{tenantID}
+	if search != "" {
+		args = append(args, escapeLike(search))
+		where += " AND prompt ILIKE '%' || $2 || '%'"
+	}
+
+	var total int64
+	if err := db.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM semantic_cache WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+        SELECT %s
+        FROM semantic_cache
+        WHERE %s
+        ORDER BY created_at DESC, id DESC
+        LIMIT $%d OFFSET $%d
+    `, cacheEntryColumns, where, len(args)+1, len(args)+2)
+
+	rows, err := db.Pool.Query(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []models.SemanticCache{}
+	for rows.Next() {
+		entry, err := scanCacheEntry(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	return entries, total, rows.Err()
+}
+
+// HasEncryptedCacheEntries reports whether any of a tenant's rows are stored
+// encrypted.
+func (db *DB) HasEncryptedCacheEntries(ctx context.Context, tenantID int) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM semantic_cache WHERE tenant_id = $1 AND data_key_version IS NOT NULL)`
+
+	var exists bool
+	err := db.Pool.QueryRow(ctx, query, tenantID).Scan(&exists)
+	return exists, err
+}
+
+// GetCacheEntry fetches one entry without counting it as a cache hit.
+func (db *DB) GetCacheEntry(ctx context.Context, tenantID int, id int64) (*models.SemanticCache, error) {
+	query := `SELECT ` + cacheEntryColumns + ` FROM semantic_cache WHERE tenant_id = $1 AND id = $2`
+	return scanCacheEntry(db.Pool.QueryRow(ctx, query, tenantID, id))
+}
+
+// DeleteCacheEntries deletes the rows matching filter inside a transaction.
+// onDeleted runs before commit with the deleted rows; if it fails the delete
+// is rolled back, which lets callers keep side stores (embeddings) in step.
+func (db *DB) DeleteCacheEntries(ctx context.Context, tenantID int, filter CacheEntryFilter, onDeleted func([]models.CacheEntryRef) error) ([]models.CacheEntryRef, error) {
+	if filter.Regex != "" {
+		return nil, errors.New("regex filters must be resolved to entry IDs first")
+	}
+	where, args := filter.where([]interface{}{tenantID})
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, "DELETE FROM semantic_cache WHERE "+where+" RETURNING "+cacheEntryRefColumns, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []models.CacheEntryRef
+	for rows.Next() {
+		var ref models.CacheEntryRef
+		if err := rows.Scan(&ref.TenantID, &ref.PromptHash, &ref.Model, &ref.NamespaceID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if onDeleted != nil {
+		if err := onDeleted(refs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+// ============ Cache Import/Export ============
+
+// StoreCachedResponses upserts many entries in one round trip.
+func (db *DB) StoreCachedResponses(ctx context.Context, entries []*models.SemanticCache, ttl time.Duration) error {
+	batch := &pgx.Batch{}
+	for _, cache := range entries {
+		batch.Queue(storeCacheEntryQueryFor(cache), storeCacheEntryArgs(cache, ttl)...)
+	}
+
+	return db.Pool.SendBatch(ctx, batch).Close()
+}
+
+// ExistingCacheHashes reports which of the given prompt hashes a tenant already has cached.
+func (db *DB) ExistingCacheHashes(ctx context.Context, tenantID int, hashes []string) (map[string]bool, error) {
+	query := `
+        SELECT prompt_hash
+        FROM semantic_cache
+        WHERE tenant_id = $1 AND prompt_hash = ANY($2)
+    `
+
+	rows, err := db.Pool.Query(ctx, query, tenantID, hashes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		existing[hash] = true
+	}
+
+	return existing, rows.Err()
+}
+
+// ListCacheEntriesAfter pages through a tenant's cache in ID order, starting
+// after afterID. Unlike OFFSET paging it stays cheap on large caches.
+func (db *DB) ListCacheEntriesAfter(ctx context.Context, tenantID int, afterID int64, limit int) ([]models.SemanticCache, error) {
+	query := `
+        SELECT ` + cacheEntryColumns + `
+        FROM semantic_cache
+        WHERE tenant_id = $1 AND id > $2
+        ORDER BY id
+        LIMIT $3
+    `
+
+	rows, err := db.Pool.Query(ctx, query, tenantID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.SemanticCache
+	for rows.Next() {
+		entry, err := scanCacheEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// UpdateCacheEntryPayload rewrites an entry's stored prompt and response,
+// e.g. after re-encrypting it with a new data key.
+func (db *DB) UpdateCacheEntryPayload(ctx context.Context, cache *models.SemanticCache) error {
+	query := `
+        UPDATE semantic_cache
+        SET prompt = $3, normalized_prompt = $4, response = $5, data_key_version = $6, compression = NULLIF($7, '')
+        WHERE tenant_id = $1 AND id = $2
+    `
+
+	_, err := db.Pool.Exec(ctx, query,
+		cache.TenantID,
+		cache.ID,
+		cache.Prompt,
+		cache.NormalizedPrompt,
+		cache.Response,
+		cache.DataKeyVersion,
+		cache.Compression,
+	)
+	return err
+}
+
+// ============ Data Keys ============
+
+// Advisory lock class for creating a tenant's data keys
+const dataKeyLockClass = 1
+
+func (db *DB) GetDataKey(ctx context.Context, tenantID, version int) (*models.DataKey, error) {
+	query := `
+        SELECT tenant_id, version, wrapped_key, active, created_at
+        FROM tenant_data_keys
+        WHERE tenant_id = $1 AND version = $2
+    `
+	return scanDataKey(db.Pool.QueryRow(ctx, query, tenantID, version))
+}
+
+func (db *DB) GetActiveDataKey(ctx context.Context, tenantID int) (*models.DataKey, error) {
+	query := `
+        SELECT tenant_id, version, wrapped_key, active, created_at
+        FROM tenant_data_keys
+        WHERE tenant_id = $1 AND active
+    `
+	return scanDataKey(db.Pool.QueryRow(ctx, query, tenantID))
+}
+
+// CreateDataKey stores a wrapped key as the tenant's next active version and
+// deactivates the others. Unless rotate is set, an existing active key is
+// returned instead, so concurrent first writes agree on one key.
+func (db *DB) CreateDataKey(ctx context.Context, tenantID int, wrappedKey []byte, rotate bool) (*models.DataKey, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1, $2)", dataKeyLockClass, tenantID); err != nil {
+		return nil, err
+	}
+
+	if !rotate {
+		existing, err := scanDataKey(tx.QueryRow(ctx, `
+            SELECT tenant_id, version, wrapped_key, active, created_at
+            FROM tenant_data_keys
+            WHERE tenant_id = $1 AND active
+        `, tenantID))
+		if err == nil {
+			return existing, tx.Commit(ctx)
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE tenant_data_keys SET active = FALSE WHERE tenant_id = $1 AND active", tenantID); err != nil {
+		return nil, err
+	}
+
+	key, err := scanDataKey(tx.QueryRow(ctx, `
+        INSERT INTO tenant_data_keys (tenant_id, version, wrapped_key)
+        SELECT $1, COALESCE(MAX(version), 0) + 1, $2 FROM tenant_data_keys WHERE tenant_id = $1
+        RETURNING tenant_id, version, wrapped_key, active, created_at
+    `, tenantID, wrappedKey))
+	if err != nil {
+		return nil, err
+	}
+
+	return key, tx.Commit(ctx)
+}
+
+func scanDataKey(row pgx.Row) (*models.DataKey, error) {
+	var key models.DataKey
+	if err := row.Scan(&key.TenantID, &key.Version, &key.WrappedKey, &key.Active, &key.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// ============ Upstream Credentials ============
+
+const tenantCredentialColumns = `id, tenant_id, name, COALESCE(backend_url, ''), location, param, prefix, wrapped_key, sealed_secret, secret_hint, version, created_at, rotated_at`
+
+func scanTenantCredential(row pgx.Row) (*models.TenantCredential, error) {
+	var cred models.TenantCredential
+	err := row.Scan(
+		&cred.ID,
+		&cred.TenantID,
+		&cred.Name,
+		&cred.BackendURL,
+		&cred.Location,
+		&cred.Param,
+		&cred.Prefix,
+		&cred.WrappedKey,
+		&cred.SealedSecret,
+		&cred.SecretHint,
+		&cred.Version,
+		&cred.CreatedAt,
+		&cred.RotatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+func (db *DB) ListTenantCredentials(ctx context.Context, tenantID int) ([]models.TenantCredential, error) {
+	query := `
+        SELECT ` + tenantCredentialColumns + `
+        FROM tenant_credentials
+        WHERE tenant_id = $1
+        ORDER BY id
+    `
+
+	rows, err := db.Pool.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	creds := []models.TenantCredential{}
+	for rows.Next() {
+		cred, err := scanTenantCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		creds = append(creds, *cred)
+	}
+
+	return creds, rows.Err()
+}
+
+func (db *DB) GetTenantCredential(ctx context.Context, tenantID, id int) (*models.TenantCredential, error) {
+	query := `SELECT ` + tenantCredentialColumns + ` FROM tenant_credentials WHERE tenant_id = $1 AND id = $2`
+	return scanTenantCredential(db.Pool.QueryRow(ctx, query, tenantID, id))
+}
+
+func (db *DB) CreateTenantCredential(ctx context.Context, cred *models.TenantCredential) error {
+	query := `
+        INSERT INTO tenant_credentials (tenant_id, name, backend_url, location, param, prefix, wrapped_key, sealed_secret, secret_hint)
+        VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9)
+        RETURNING id, version, created_at, rotated_at
+    `
+
+	return db.Pool.QueryRow(ctx, query,
+		cred.TenantID,
+		cred.Name,
+		cred.BackendURL,
+		cred.Location,
+		cred.Param,
+		cred.Prefix,
+		cred.WrappedKey,
+		cred.SealedSecret,
+		cred.SecretHint,
+	).Scan(&cred.ID, &cred.Version, &cred.CreatedAt, &cred.RotatedAt)
+}
+
+// UpdateTenantCredential changes where and how a credential is injected,
+// together with its secret, sealed again for the new rule.
+func (db *DB) UpdateTenantCredential(ctx context.Context, cred *models.TenantCredential) error {
+	query := `
+        UPDATE tenant_credentials
+        SET name = $3, backend_url = NULLIF($4, ''), location = $5, param = $6, prefix = $7, wrapped_key = $8, sealed_secret = $9
+        WHERE tenant_id = $1 AND id = $2
+    `
+
+	tag, err := db.Pool.Exec(ctx, query,
+		cred.TenantID,
+		cred.ID,
+		cred.Name,
+		cred.BackendURL,
+		cred.Location,
+		cred.Param,
+		cred.Prefix,
+		cred.WrappedKey,
+		cred.SealedSecret,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// RotateTenantCredential replaces a credential's secret and bumps its version.
+func (db *DB) RotateTenantCredential(ctx context.Context, cred *models.TenantCredential) error {
+	query := `
+        UPDATE tenant_credentials
+        SET wrapped_key = $3, sealed_secret = $4, secret_hint = $5, version = version + 1, rotated_at = NOW()
+        WHERE tenant_id = $1 AND id = $2
+        RETURNING version, rotated_at
+    `
+
+	return db.Pool.QueryRow(ctx, query,
+		cred.TenantID,
+		cred.ID,
+		cred.WrappedKey,
+		cred.SealedSecret,
+		cred.SecretHint,
+	).Scan(&cred.Version, &cred.RotatedAt)
+}
+
+func (db *DB) DeleteTenantCredential(ctx context.Context, tenantID, id int) error {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM tenant_credentials WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
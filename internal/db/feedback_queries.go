@@ -0,0 +1,238 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// ============ Cache Hit Feedback ============
+
+const cacheHitEventColumns = `id, tenant_id, entry_id, prompt_hash, entry_prompt_hash, similarity, semantic, namespace_id,
+    COALESCE(model, ''), prompt_tokens, completion_tokens, cost_saved::float8, feedback, feedback_at, created_at`
+
+func scanCacheHitEvent(row pgx.Row) (*models.CacheHitEvent, error) {
+	var event models.CacheHitEvent
+	err := row.Scan(
+		&event.ID,
+		&event.TenantID,
+		&event.EntryID,
+		&event.PromptHash,
+		&event.EntryPromptHash,
+		&event.Similarity,
+		&event.Semantic,
+		&event.NamespaceID,
+		&event.Model,
+		&event.PromptTokens,
+		&event.CompletionTokens,
+		&event.CostSaved,
+		&event.Feedback,
+		&event.FeedbackAt,
+		&event.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// RecordCacheHit stores a hit and adds what it saved to the tenant's daily totals.
+func (db *DB) RecordCacheHit(ctx context.Context, event *models.CacheHitEvent) error {
+	query := `
+        WITH event AS (
+            INSERT INTO cache_hit_events (tenant_id, entry_id, prompt_hash, entry_prompt_hash, similarity, semantic, namespace_id,
+                model, prompt_tokens, completion_tokens, cost_saved)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, $11)
+            RETURNING id, created_at
+        ), rollup AS (
+            INSERT INTO cache_savings_daily (tenant_id, day, hits, priced_hits, prompt_tokens, completion_tokens, cost_saved)
+            SELECT $1, created_at::date, 1, CASE WHEN $11::numeric IS NULL THEN 0 ELSE 1 END, $9, $10, COALESCE($11::numeric, 0)
+            FROM event
+            ON CONFLICT (tenant_id, day) DO UPDATE
+            SET hits = cache_savings_daily.hits + 1,
+                priced_hits = cache_savings_daily.priced_hits + EXCLUDED.priced_hits,
+                prompt_tokens = cache_savings_daily.prompt_tokens + EXCLUDED.prompt_tokens,
+                completion_tokens = cache_savings_daily.completion_tokens + EXCLUDED.completion_tokens,
+                cost_saved = cache_savings_daily.cost_saved + EXCLUDED.cost_saved
+        )
+        SELECT id, created_at FROM event
+    `
+
+	return db.Pool.QueryRow(ctx, query,
+		event.TenantID,
+		event.EntryID,
+		event.PromptHash,
+		event.EntryPromptHash,
+		event.Similarity,
+		event.Semantic,
+		event.NamespaceID,
+		event.Model,
+		event.PromptTokens,
+		event.CompletionTokens,
+		event.CostSaved,
+	).Scan(&event.ID, &event.CreatedAt)
+}
+
+// ReportBadCacheHit marks the latest unreported hit of an entry as bad. A
+// non-empty promptHash narrows it down to hits for that request prompt.
+func (db *DB) ReportBadCacheHit(ctx context.Context, tenantID int, entryID int64, promptHash string) (*models.CacheHitEvent, error) {
+	query := `
+        UPDATE cache_hit_events
+        SET feedback = 'bad', feedback_at = NOW()
+        WHERE id = (
+            SELECT id FROM cache_hit_events
+            WHERE tenant_id = $1 AND entry_id = $2 AND ($3 = '' OR prompt_hash = $3) AND feedback IS NULL
+            ORDER BY created_at DESC
+            LIMIT 1
+        )
+        RETURNING ` + cacheHitEventColumns
+
+	return scanCacheHitEvent(db.Pool.QueryRow(ctx, query, tenantID, entryID, promptHash))
+}
+
+// CountBadCacheHits counts bad reports for one prompt/entry pair.
+func (db *DB) CountBadCacheHits(ctx context.Context, tenantID int, promptHash, entryPromptHash string) (int, error) {
+	query := `
+        SELECT COUNT(*)
+        FROM cache_hit_events
+        WHERE tenant_id = $1 AND prompt_hash = $2 AND entry_prompt_hash = $3 AND feedback = 'bad'
+    `
+
+	var count int
+	err := db.Pool.QueryRow(ctx, query, tenantID, promptHash, entryPromptHash).Scan(&count)
+	return count, err
+}
+
+func (db *DB) BlacklistCachePair(ctx context.Context, tenantID int, promptHash, entryPromptHash string) error {
+	query := `
+        INSERT INTO cache_blacklist (tenant_id, prompt_hash, entry_prompt_hash)
+        VALUES ($1, $2, $3)
+        ON CONFLICT DO NOTHING
+    `
+
+	_, err := db.Pool.Exec(ctx, query, tenantID, promptHash, entryPromptHash)
+	return err
+}
+
+// BlacklistedCacheHashes returns the entries a request prompt must not be answered with.
+func (db *DB) BlacklistedCacheHashes(ctx context.Context, tenantID int, promptHash string) (map[string]bool, error) {
+	query := `SELECT entry_prompt_hash FROM cache_blacklist WHERE tenant_id = $1 AND prompt_hash = $2`
+
+	rows, err := db.Pool.Query(ctx, query, tenantID, promptHash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blacklisted := make(map[string]bool)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		blacklisted[hash] = true
+	}
+	return blacklisted, rows.Err()
+}
+
+// BadSemanticHitSimilarities returns the similarities of semantic hits
+// reported as bad since a point in time (nil for all time) that a threshold
+// of minSimilarity would still let through.
+func (db *DB) BadSemanticHitSimilarities(ctx context.Context, tenantID int, minSimilarity float64, since *time.Time) ([]float64, error) {
+	query := `
+        SELECT similarity
+        FROM cache_hit_events
+        WHERE tenant_id = $1 AND semantic AND feedback = 'bad' AND similarity >= $2
+          AND ($3::timestamp IS NULL OR feedback_at > $3)
+        ORDER BY similarity
+    `
+
+	rows, err := db.Pool.Query(ctx, query, tenantID, minSimilarity, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var similarities []float64
+	for rows.Next() {
+		var similarity float64
+		if err := rows.Scan(&similarity); err != nil {
+			return nil, err
+		}
+		similarities = append(similarities, similarity)
+	}
+	return similarities, rows.Err()
+}
+
+// RaiseSimilarityThreshold sets a tenant's threshold, never lowering it.
+func (db *DB) RaiseSimilarityThreshold(ctx context.Context, tenantID int, threshold float64) error {
+	query := `
+        INSERT INTO tenant_cache_settings (tenant_id, similarity_threshold, threshold_adjusted_at)
+        VALUES ($1, $2, NOW())
+        ON CONFLICT (tenant_id) DO UPDATE
+        SET similarity_threshold = GREATEST(COALESCE(tenant_cache_settings.similarity_threshold, 0), EXCLUDED.similarity_threshold),
+            threshold_adjusted_at = NOW(),
+            updated_at = NOW()
+    `
+
+	_, err := db.Pool.Exec(ctx, query, tenantID, threshold)
+	return err
+}
+
+// GetCacheFeedbackStats estimates semantic hit precision over the last 30
+// days, overall and per similarity band, for one tenant or all (tenantID 0).
+// Hits nobody reported count as correct, so these are upper bounds.
+func (db *DB) GetCacheFeedbackStats(ctx context.Context, tenantID int) (map[string]interface{}, error) {
+	query := `
+        SELECT FLOOR(similarity * 50) / 50 AS band,
+               COUNT(*),
+               COUNT(*) FILTER (WHERE feedback = 'bad')
+        FROM cache_hit_events
+        WHERE semantic AND created_at > NOW() - INTERVAL '30 days' AND ($1 = 0 OR tenant_id = $1)
+        GROUP BY band
+        ORDER BY band
+    `
+
+	rows, err := db.Pool.Query(ctx, query, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits, bad int64
+	bands := []map[string]interface{}{}
+	for rows.Next() {
+		var band float64
+		var bandHits, bandBad int64
+		if err := rows.Scan(&band, &bandHits, &bandBad); err != nil {
+			return nil, err
+		}
+		hits += bandHits
+		bad += bandBad
+		bands = append(bands, map[string]interface{}{
+			"min_similarity":      band,
+			"hits":                bandHits,
+			"reported_bad":        bandBad,
+			"estimated_precision": precision(bandHits, bandBad),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"semantic_hits":       hits,
+		"reported_bad":        bad,
+		"estimated_precision": precision(hits, bad),
+		"by_similarity":       bands,
+	}, nil
+}
+
+func precision(hits, bad int64) interface{} {
+	if hits == 0 {
+		return nil
+	}
+	return float64(hits-bad) / float64(hits)
+}
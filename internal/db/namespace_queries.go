@@ -0,0 +1,175 @@
+package db
+
+import (
+	"context"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// ============ Shared Cache Namespaces ============
+
+func (db *DB) CreateCacheNamespace(ctx context.Context, namespace *models.CacheNamespace) error {
+	query := `
+        INSERT INTO cache_namespaces (name, description)
+        VALUES ($1, $2)
+        RETURNING id, created_at
+    `
+
+	return db.Pool.QueryRow(ctx, query, namespace.Name, namespace.Description).Scan(&namespace.ID, &namespace.CreatedAt)
+}
+
+func (db *DB) ListCacheNamespaces(ctx context.Context) ([]models.CacheNamespace, error) {
+	query := `
+        SELECT id, name, description, created_at
+        FROM cache_namespaces
+        ORDER BY name
+    `
+
+	rows, err := db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	namespaces := []models.CacheNamespace{}
+	for rows.Next() {
+		var namespace models.CacheNamespace
+		if err := rows.Scan(&namespace.ID, &namespace.Name, &namespace.Description, &namespace.CreatedAt); err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, namespace)
+	}
+
+	return namespaces, rows.Err()
+}
+
+// GetCacheNamespace returns a namespace along with its members.
+func (db *DB) GetCacheNamespace(ctx context.Context, id int) (*models.CacheNamespace, error) {
+	query := `SELECT id, name, description, created_at FROM cache_namespaces WHERE id = $1`
+
+	var namespace models.CacheNamespace
+	err := db.Pool.QueryRow(ctx, query, id).Scan(&namespace.ID, &namespace.Name, &namespace.Description, &namespace.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := db.queryNamespaceMembers(ctx, "m.namespace_id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	namespace.Members = members
+
+	return &namespace, nil
+}
+
+// DeleteCacheNamespace drops a namespace, its memberships and its entries.
+func (db *DB) DeleteCacheNamespace(ctx context.Context, id int) error {
+	tag, err := db.Pool.Exec(ctx, `DELETE FROM cache_namespaces WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// PutCacheNamespaceMember adds a tenant to a namespace or changes its access.
+func (db *DB) PutCacheNamespaceMember(ctx context.Context, member *models.CacheNamespaceMember) error {
+	query := `
+        INSERT INTO cache_namespace_members (namespace_id, tenant_id, can_read, can_write)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (namespace_id, tenant_id) DO UPDATE
+        SET can_read = EXCLUDED.can_read, can_write = EXCLUDED.can_write
+        RETURNING created_at
+    `
+
+	return db.Pool.QueryRow(ctx, query,
+		member.NamespaceID,
+		member.TenantID,
+		member.CanRead,
+		member.CanWrite,
+	).Scan(&member.CreatedAt)
+}
+
+func (db *DB) DeleteCacheNamespaceMember(ctx context.Context, namespaceID, tenantID int) error {
+	query := `DELETE FROM cache_namespace_members WHERE namespace_id = $1 AND tenant_id = $2`
+
+	tag, err := db.Pool.Exec(ctx, query, namespaceID, tenantID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// ListTenantNamespaces returns every namespace membership a tenant holds.
+func (db *DB) ListTenantNamespaces(ctx context.Context, tenantID int) ([]models.CacheNamespaceMember, error) {
+	return db.queryNamespaceMembers(ctx, "m.tenant_id = $1", tenantID)
+}
+
+func (db *DB) queryNamespaceMembers(ctx context.Context, where string, arg int) ([]models.CacheNamespaceMember, error) {
+	query := `
+        SELECT m.namespace_id, n.name, m.tenant_id, m.can_read, m.can_write, m.created_at
+        FROM cache_namespace_members m
+        JOIN cache_namespaces n ON n.id = m.namespace_id
+        WHERE ` + where + `
+        ORDER BY m.namespace_id, m.tenant_id
+    `
+
+	rows, err := db.Pool.Query(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.CacheNamespaceMember{}
+	for rows.Next() {
+		var member models.CacheNamespaceMember
+		err := rows.Scan(
+			&member.NamespaceID,
+			&member.Namespace,
+			&member.TenantID,
+			&member.CanRead,
+			&member.CanWrite,
+			&member.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// CountCacheEntriesByNamespace returns the number of cache rows held by each namespace.
+func (db *DB) CountCacheEntriesByNamespace(ctx context.Context) (map[int]int64, error) {
+	query := `
+        SELECT namespace_id, COUNT(*)
+        FROM semantic_cache
+        WHERE namespace_id IS NOT NULL
+        GROUP BY namespace_id
+    `
+
+	rows, err := db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int64)
+	for rows.Next() {
+		var namespaceID int
+		var count int64
+		if err := rows.Scan(&namespaceID, &count); err != nil {
+			return nil, err
+		}
+		counts[namespaceID] = count
+	}
+
+	return counts, rows.Err()
+}
@@ -12,7 +12,7 @@ import (
 
 func (db *DB) GetTenantByAPIKey(ctx context.Context, apiKey string) (*models.Tenant, error) {
 	query := `
-        SELECT id, name, api_key, rate_limit_per_hour, backend_url, created_at, updated_at
+        SELECT id, name, api_key, rate_limit_per_hour, backend_url, lb_strategy, COALESCE(lb_hash_header, ''), api_format, created_at, updated_at
         FROM tenants
         WHERE api_key = $1
     `
@@ -24,6 +24,9 @@ func (db *DB) GetTenantByAPIKey(ctx context.Context, apiKey string) (*models.Ten
 		&tenant.APIKey,
 		&tenant.RateLimitPerHour,
 		&tenant.BackendURL,
+		&tenant.LBStrategy,
+		&tenant.LBHashHeader,
+		&tenant.APIFormat,
 		&tenant.CreatedAt,
 		&tenant.UpdatedAt,
 	)
@@ -37,8 +40,8 @@ func (db *DB) GetTenantByAPIKey(ctx context.Context, apiKey string) (*models.Ten
 
 func (db *DB) LogAccess(ctx context.Context, log *models.AccessLog) error {
 	query := `
-        INSERT INTO access_logs (tenant_id, endpoint, method, status_code, response_time_ms, request_size, response_size)
-        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        INSERT INTO access_logs (tenant_id, endpoint, method, status_code, response_time_ms, request_size, response_size, ttfb_ms, backend, provider, fallback_step)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), $11)
     `
 
 	_, err := db.Pool.Exec(ctx, query,
@@ -49,54 +52,105 @@ func (db *DB) LogAccess(ctx context.Context, log *models.AccessLog) error {
 		log.ResponseTimeMs,
 		log.RequestSize,
 		log.ResponseSize,
+		log.TTFBMs,
+		log.Backend,
+		log.Provider,
+		log.FallbackStep,
 	)
 
 	return err
 }
 
-func (db *DB) GetCachedResponse(ctx context.Context, tenantID int, promptHash string) (*models.SemanticCache, error) {
+// GetCachedResponse returns a live entry and counts the hit. A non-zero maxAge
+// skips entries cached longer ago than that. Expired rows are never returned,
+// ExpireCachedResponse deletes them.
+func (db *DB) GetCachedResponse(ctx context.Context, tenantID int, promptHash string, maxAge time.Duration) (*models.SemanticCache, error) {
 	query := `
         UPDATE semantic_cache
         SET hit_count = hit_count + 1, last_accessed = NOW()
         WHERE tenant_id = $1 AND prompt_hash = $2
-        RETURNING id, tenant_id, prompt_hash, prompt, response, embedding_stored, hit_count, created_at, last_accessed
-    `
-
-	var cache models.SemanticCache
-	err := db.Pool.QueryRow(ctx, query, tenantID, promptHash).Scan(
-		&cache.ID,
-		&cache.TenantID,
-		&cache.PromptHash,
-		&cache.Prompt,
-		&cache.Response,
-		&cache.EmbeddingStored,
-		&cache.HitCount,
-		&cache.CreatedAt,
-		&cache.LastAccessed,
-	)
+        AND (expires_at IS NULL OR expires_at > NOW())
+        AND ($3::int = 0 OR created_at > NOW() - $3::int * INTERVAL '1 second')
+        RETURNING ` + cacheEntryColumns
 
-	if err != nil {
-		return nil, err
-	}
-
-	return &cache, nil
+	return scanCacheEntry(db.Pool.QueryRow(ctx, query, tenantID, promptHash, int(maxAge.Seconds())))
 }
 
-func (db *DB) StoreCachedResponse(ctx context.Context, cache *models.SemanticCache) error {
+// GetNamespaceCachedResponse is GetCachedResponse for the shared namespaces a
+// tenant reads from, preferring the most recently cached entry.
+func (db *DB) GetNamespaceCachedResponse(ctx context.Context, namespaceIDs []int, promptHash string, maxAge time.Duration) (*models.SemanticCache, error) {
 	query := `
-        INSERT INTO semantic_cache (tenant_id, prompt_hash, prompt, response, embedding_stored)
-        VALUES ($1, $2, $3, $4, $5)
-        ON CONFLICT (prompt_hash) DO UPDATE
-        SET response = EXCLUDED.response, last_accessed = NOW()
+        UPDATE semantic_cache
+        SET hit_count = hit_count + 1, last_accessed = NOW()
+        WHERE id = (
+            SELECT id FROM semantic_cache
+            WHERE namespace_id = ANY($1) AND prompt_hash = $2
+            AND (expires_at IS NULL OR expires_at > NOW())
+            AND ($3::int = 0 OR created_at > NOW() - $3::int * INTERVAL '1 second')
+            ORDER BY created_at DESC
+            LIMIT 1
+        )
+        RETURNING ` + cacheEntryColumns
+
+	return scanCacheEntry(db.Pool.QueryRow(ctx, query, namespaceIDs, promptHash, int(maxAge.Seconds())))
+}
+
+// Private entries are unique per tenant, shared ones per namespace
+const (
+	storeCacheEntryQuery     = storeCacheEntryInsert + `ON CONFLICT (tenant_id, prompt_hash) WHERE namespace_id IS NULL` + storeCacheEntryUpdate
+	storeNamespaceEntryQuery = storeCacheEntryInsert + `ON CONFLICT (namespace_id, prompt_hash) WHERE namespace_id IS NOT NULL` + storeCacheEntryUpdate
+)
+
+const storeCacheEntryInsert = `
+        INSERT INTO semantic_cache (tenant_id, prompt_hash, prompt, normalized_prompt, response, status_code, response_headers, content_encoding,
+            embedding_stored, data_key_version, compression, expires_at, namespace_id, source_tenant_id, model)
+        VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, NULLIF($11, ''), CASE WHEN $12::int > 0 THEN NOW() + $12::int * INTERVAL '1 second' END,
+            $13, $14, $15)
+        `
+
+const storeCacheEntryUpdate = ` DO UPDATE
+        SET prompt = EXCLUDED.prompt, normalized_prompt = EXCLUDED.normalized_prompt, response = EXCLUDED.response,
+            status_code = EXCLUDED.status_code, response_headers = EXCLUDED.response_headers, content_encoding = EXCLUDED.content_encoding,
+            data_key_version = EXCLUDED.data_key_version, compression = EXCLUDED.compression,
+            source_tenant_id = EXCLUDED.source_tenant_id,
+            created_at = NOW(), last_accessed = NOW(), expires_at = EXCLUDED.expires_at
     `
 
-	_, err := db.Pool.Exec(ctx, query,
+func storeCacheEntryQueryFor(cache *models.SemanticCache) string {
+	if cache.NamespaceID != nil {
+		return storeNamespaceEntryQuery
+	}
+	return storeCacheEntryQuery
+}
+
+func storeCacheEntryArgs(cache *models.SemanticCache, ttl time.Duration) []interface{} {
+	statusCode := cache.StatusCode
+	if statusCode == 0 {
+		statusCode = 200
+	}
+
+	return []interface{}{
 		cache.TenantID,
 		cache.PromptHash,
 		cache.Prompt,
+		cache.NormalizedPrompt,
 		cache.Response,
+		statusCode,
+		cache.ResponseHeaders,
+		cache.ContentEncoding,
 		cache.EmbeddingStored,
-	)
+		cache.DataKeyVersion,
+		cache.Compression,
+		int(ttl.Seconds()),
+		cache.NamespaceID,
+		cache.SourceTenantID,
+		cache.Model,
+	}
+}
+
+// StoreCachedResponse upserts a cache entry. A ttl of zero stores an entry that never expires.
+func (db *DB) StoreCachedResponse(ctx context.Context, cache *models.SemanticCache, ttl time.Duration) error {
+	_, err := db.Pool.Exec(ctx, storeCacheEntryQueryFor(cache), storeCacheEntryArgs(cache, ttl)...)
 
 	return err
 }
@@ -105,9 +159,9 @@ func (db *DB) StoreCachedResponse(ctx context.Context, cache *models.SemanticCac
 
 func (db *DB) CreateTenant(ctx context.Context, tenant *models.Tenant) error {
 	query := `
-        INSERT INTO tenants (name, api_key, rate_limit_per_hour, backend_url)
-        VALUES ($1, $2, $3, $4)
-        RETURNING id, created_at, updated_at
+        INSERT INTO tenants (name, api_key, rate_limit_per_hour, backend_url, api_format)
+        VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5, ''), 'openai'))
+        RETURNING id, lb_strategy, api_format, created_at, updated_at
     `
 
 	err := db.Pool.QueryRow(ctx, query,
@@ -115,14 +169,15 @@ func (db *DB) CreateTenant(ctx context.Context, tenant *models.Tenant) error {
 		tenant.APIKey,
 		tenant.RateLimitPerHour,
 		tenant.BackendURL,
-	).Scan(&tenant.ID, &tenant.CreatedAt, &tenant.UpdatedAt)
+		tenant.APIFormat,
+	).Scan(&tenant.ID, &tenant.LBStrategy, &tenant.APIFormat, &tenant.CreatedAt, &tenant.UpdatedAt)
 
 	return err
 }
 
 func (db *DB) ListTenants(ctx context.Context) ([]models.Tenant, error) {
 	query := `
-        SELECT id, name, api_key, rate_limit_per_hour, backend_url, created_at, updated_at
+        SELECT id, name, api_key, rate_limit_per_hour, backend_url, lb_strategy, COALESCE(lb_hash_header, ''), api_format, created_at, updated_at
         FROM tenants
         ORDER BY created_at DESC
     `
@@ -142,6 +197,9 @@ func (db *DB) ListTenants(ctx context.Context) ([]models.Tenant, error) {
 			&tenant.APIKey,
 			&tenant.RateLimitPerHour,
 			&tenant.BackendURL,
+			&tenant.LBStrategy,
+			&tenant.LBHashHeader,
+			&tenant.APIFormat,
 			&tenant.CreatedAt,
 			&tenant.UpdatedAt,
 		)
@@ -156,7 +214,7 @@ func (db *DB) ListTenants(ctx context.Context) ([]models.Tenant, error) {
 
 func (db *DB) GetTenantByID(ctx context.Context, id int) (*models.Tenant, error) {
 	query := `
-        SELECT id, name, api_key, rate_limit_per_hour, backend_url, created_at, updated_at
+        SELECT id, name, api_key, rate_limit_per_hour, backend_url, lb_strategy, COALESCE(lb_hash_header, ''), api_format, created_at, updated_at
         FROM tenants
         WHERE id = $1
     `
@@ -168,6 +226,9 @@ func (db *DB) GetTenantByID(ctx context.Context, id int) (*models.Tenant, error)
 		&tenant.APIKey,
 		&tenant.RateLimitPerHour,
 		&tenant.BackendURL,
+		&tenant.LBStrategy,
+		&tenant.LBHashHeader,
+		&tenant.APIFormat,
 		&tenant.CreatedAt,
 		&tenant.UpdatedAt,
 	)
@@ -210,22 +271,37 @@ func (db *DB) UpdateTenant(ctx context.Context, id int, updates interface{}) err
 	argCount := 1
 
 	if name, ok := updateMap["name"]; ok {
-		query += ", name = $" + string(rune(argCount+'0'))
+		query += fmt.Sprintf(", name = $%d", argCount)
 		args = append(args, name)
 		argCount++
 	}
 	if backendURL, ok := updateMap["backend_url"]; ok {
-		query += ", backend_url = $" + string(rune(argCount+'0'))
+		query += fmt.Sprintf(", backend_url = $%d", argCount)
 		args = append(args, backendURL)
 		argCount++
 	}
 	if rateLimit, ok := updateMap["rate_limit_per_hour"]; ok {
-		query += ", rate_limit_per_hour = $" + string(rune(argCount+'0'))
+		query += fmt.Sprintf(", rate_limit_per_hour = $%d", argCount)
 		args = append(args, rateLimit)
 		argCount++
 	}
+	if strategy, ok := updateMap["lb_strategy"]; ok {
+		query += fmt.Sprintf(", lb_strategy = $%d", argCount)
+		args = append(args, strategy)
+		argCount++
+	}
+	if hashHeader, ok := updateMap["lb_hash_header"]; ok {
+		query += fmt.Sprintf(", lb_hash_header = NULLIF($%d, '')", argCount)
+		args = append(args, hashHeader)
+		argCount++
+	}
+	if format, ok := updateMap["api_format"]; ok {
+		query += fmt.Sprintf(", api_format = $%d", argCount)
+		args = append(args, format)
+		argCount++
+	}
 
-	query += " WHERE id = $" + string(rune(argCount+'0'))
+	query += fmt.Sprintf(" WHERE id = $%d", argCount)
 	args = append(args, id)
 
 	_, err := db.Pool.Exec(ctx, query, args...)
@@ -262,6 +338,8 @@ func (db *DB) GetTenantAnalytics(ctx context.Context, tenantID int, from, to str
         SELECT 
             COALESCE(COUNT(*), 0) as total_requests,
             COALESCE(AVG(response_time_ms), 0) as avg_response_time,
+            COALESCE(AVG(ttfb_ms), 0) as avg_ttfb,
+            COALESCE(PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY ttfb_ms), 0) as p95_ttfb,
             COALESCE(SUM(request_size), 0) as total_request_size,
             COALESCE(SUM(response_size), 0) as total_response_size,
             COALESCE(COUNT(CASE WHEN status_code >= 200 AND status_code < 300 THEN 1 END), 0) as success_count,
@@ -275,6 +353,8 @@ func (db *DB) GetTenantAnalytics(ctx context.Context, tenantID int, from, to str
 	var stats struct {
 		TotalRequests     int64
 		AvgResponseTime   float64
+		AvgTTFB           float64
+		P95TTFB           float64
 		TotalRequestSize  int64
 		TotalResponseSize int64
 		SuccessCount      int64
@@ -284,6 +364,8 @@ func (db *DB) GetTenantAnalytics(ctx context.Context, tenantID int, from, to str
 	err := db.Pool.QueryRow(ctx, statsQuery, tenantID, from, to).Scan(
 		&stats.TotalRequests,
 		&stats.AvgResponseTime,
+		&stats.AvgTTFB,
+		&stats.P95TTFB,
 		&stats.TotalRequestSize,
 		&stats.TotalResponseSize,
 		&stats.SuccessCount,
@@ -324,6 +406,110 @@ func (db *DB) GetTenantAnalytics(ctx context.Context, tenantID int, from, to str
 		})
 	}
 
+	// Cache hits split between the tenant's own cache and each shared namespace
+	sourcesQuery := `
+        SELECT COALESCE(n.name, 'private') as source, COUNT(*) as hits, COUNT(CASE WHEN e.semantic THEN 1 END) as semantic_hits
+        FROM cache_hit_events e
+        LEFT JOIN cache_namespaces n ON n.id = e.namespace_id
+        WHERE e.tenant_id = $1
+        AND e.created_at >= $2::date
+        AND e.created_at < $3::date
+        GROUP BY source
+        ORDER BY hits DESC
+    `
+
+	sourceRows, err := db.Pool.Query(ctx, sourcesQuery, tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cache hit sources: %w", err)
+	}
+	defer sourceRows.Close()
+
+	cacheHits := []map[string]interface{}{}
+	for sourceRows.Next() {
+		var source string
+		var hits, semanticHits int64
+		if err := sourceRows.Scan(&source, &hits, &semanticHits); err != nil {
+			continue
+		}
+		cacheHits = append(cacheHits, map[string]interface{}{
+			"source":        source,
+			"hits":          hits,
+			"semantic_hits": semanticHits,
+		})
+	}
+
+	// Requests per backend of the tenant's pool
+	backendsQuery := `
+        SELECT backend, COUNT(*) as count, COALESCE(AVG(response_time_ms), 0) as avg_response_time,
+            COUNT(CASE WHEN status_code >= 500 THEN 1 END) as error_count
+        FROM access_logs
+        WHERE tenant_id = $1
+        AND timestamp >= $2::date
+        AND timestamp < $3::date
+        AND backend IS NOT NULL
+        GROUP BY backend
+        ORDER BY count DESC
+    `
+
+	backendRows, err := db.Pool.Query(ctx, backendsQuery, tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query backends: %w", err)
+	}
+	defer backendRows.Close()
+
+	backends := []map[string]interface{}{}
+	for backendRows.Next() {
+		var backend string
+		var count, errorCount int64
+		var avgResponseTime float64
+		if err := backendRows.Scan(&backend, &count, &avgResponseTime, &errorCount); err != nil {
+			continue
+		}
+		backends = append(backends, map[string]interface{}{
+			"backend":              backend,
+			"count":                count,
+			"avg_response_time_ms": avgResponseTime,
+			"error_count":          errorCount,
+		})
+	}
+
+	// Requests per answering provider, and how many of them were fallbacks
+	providersQuery := `
+        SELECT provider, COUNT(*) as count, COUNT(fallback_step) as fallback_count
+        FROM access_logs
+        WHERE tenant_id = $1
+        AND timestamp >= $2::date
+        AND timestamp < $3::date
+        AND provider IS NOT NULL
+        GROUP BY provider
+        ORDER BY count DESC
+    `
+
+	providerRows, err := db.Pool.Query(ctx, providersQuery, tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query providers: %w", err)
+	}
+	defer providerRows.Close()
+
+	providers := []map[string]interface{}{}
+	for providerRows.Next() {
+		var provider string
+		var count, fallbackCount int64
+		if err := providerRows.Scan(&provider, &count, &fallbackCount); err != nil {
+			continue
+		}
+		providers = append(providers, map[string]interface{}{
+			"provider":       provider,
+			"count":          count,
+			"fallback_count": fallbackCount,
+		})
+	}
+
+	savings, err := db.getCacheSavings(ctx, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
 	successRate := 0.0
 	if stats.TotalRequests > 0 {
 		successRate = float64(stats.SuccessCount) / float64(stats.TotalRequests) * 100
@@ -332,12 +518,18 @@ func (db *DB) GetTenantAnalytics(ctx context.Context, tenantID int, from, to str
 	return map[string]interface{}{
 		"total_requests":       stats.TotalRequests,
 		"avg_response_time_ms": stats.AvgResponseTime,
+		"avg_ttfb_ms":          stats.AvgTTFB,
+		"p95_ttfb_ms":          stats.P95TTFB,
 		"total_request_size":   stats.TotalRequestSize,
 		"total_response_size":  stats.TotalResponseSize,
 		"success_count":        stats.SuccessCount,
 		"error_count":          stats.ErrorCount,
 		"success_rate":         successRate,
 		"top_endpoints":        topEndpoints,
+		"backends":             backends,
+		"providers":            providers,
+		"cache_hits_by_source": cacheHits,
+		"cache_savings":        savings,
 		"time_range": map[string]string{
 			"from": from,
 			"to":   to,
@@ -345,6 +537,64 @@ func (db *DB) GetTenantAnalytics(ctx context.Context, tenantID int, from, to str
 	}, nil
 }
 
+// getCacheSavings totals the tokens and estimated cost cache hits saved a
+// tenant, overall and per day.
+func (db *DB) getCacheSavings(ctx context.Context, tenantID int, from, to string) (map[string]interface{}, error) {
+	query := `
+        SELECT day::text, hits, priced_hits, prompt_tokens, completion_tokens, cost_saved::float8
+        FROM cache_savings_daily
+        WHERE tenant_id = $1
+        AND day >= $2::date
+        AND day < $3::date
+        ORDER BY day
+    `
+
+	rows, err := db.Pool.Query(ctx, query, tenantID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cache savings: %w", err)
+	}
+	defer rows.Close()
+
+	var hits, pricedHits, promptTokens, completionTokens int64
+	var costSaved float64
+	daily := []map[string]interface{}{}
+	for rows.Next() {
+		var day string
+		var dayHits, dayPriced, dayPrompt, dayCompletion int64
+		var dayCost float64
+		if err := rows.Scan(&day, &dayHits, &dayPriced, &dayPrompt, &dayCompletion, &dayCost); err != nil {
+			return nil, fmt.Errorf("failed to read cache savings: %w", err)
+		}
+
+		hits += dayHits
+		pricedHits += dayPriced
+		promptTokens += dayPrompt
+		completionTokens += dayCompletion
+		costSaved += dayCost
+
+		daily = append(daily, map[string]interface{}{
+			"date":              day,
+			"hits":              dayHits,
+			"prompt_tokens":     dayPrompt,
+			"completion_tokens": dayCompletion,
+			"cost_saved_usd":    dayCost,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cache savings: %w", err)
+	}
+
+	return map[string]interface{}{
+		"hits":              hits,
+		"priced_hits":       pricedHits,
+		"prompt_tokens":     promptTokens,
+		"completion_tokens": completionTokens,
+		"tokens_saved":      promptTokens + completionTokens,
+		"cost_saved_usd":    costSaved,
+		"daily":             daily,
+	}, nil
+}
+
 func (db *DB) GetCacheStats(ctx context.Context) (map[string]interface{}, error) {
 	query := `
         SELECT 
@@ -374,7 +624,7 @@ func (db *DB) GetCacheStats(ctx context.Context) (map[string]interface{}, error)
 
 	// Get top cached queries
 	topQuery := `
-        SELECT prompt, hit_count, last_accessed
+        SELECT CASE WHEN data_key_version IS NULL THEN prompt ELSE '[encrypted]' END, hit_count, last_accessed
         FROM semantic_cache
         ORDER BY hit_count DESC
         LIMIT 10
@@ -0,0 +1,114 @@
+package embedding
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Batcher merges concurrent Embed calls into fewer, larger upstream requests.
+// A batch is sent once it holds maxBatch texts or maxWait has passed since
+// its first text arrived.
+type Batcher struct {
+	inner    Embedder
+	maxBatch int
+	maxWait  time.Duration
+
+	mu      sync.Mutex
+	pending []*batchRequest
+	size    int
+	timer   *time.Timer
+}
+
+type batchRequest struct {
+	texts  []string
+	result chan batchResult
+}
+
+type batchResult struct {
+	vectors [][]float64
+	err     error
+}
+
+func NewBatcher(inner Embedder, maxBatch int, maxWait time.Duration) *Batcher {
+	return &Batcher{inner: inner, maxBatch: maxBatch, maxWait: maxWait}
+}
+
+func (b *Batcher) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) >= b.maxBatch {
+		return b.inner.Embed(ctx, texts)
+	}
+
+	req := &batchRequest{texts: texts, result: make(chan batchResult, 1)}
+
+	b.mu.Lock()
+	b.pending = append(b.pending, req)
+	b.size += len(texts)
+	if b.size >= b.maxBatch {
+		batch := b.take()
+		b.mu.Unlock()
+		go b.send(batch)
+	} else {
+		if b.timer == nil {
+			b.timer = time.AfterFunc(b.maxWait, b.flush)
+		}
+		b.mu.Unlock()
+	}
+
+	select {
+	case res := <-req.result:
+		return res.vectors, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// take empties the pending batch, b.mu must be held
+func (b *Batcher) take() []*batchRequest {
+	batch := b.pending
+	b.pending = nil
+	b.size = 0
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	return batch
+}
+
+func (b *Batcher) flush() {
+	b.mu.Lock()
+	batch := b.take()
+	b.mu.Unlock()
+
+	if len(batch) > 0 {
+		b.send(batch)
+	}
+}
+
+func (b *Batcher) send(batch []*batchRequest) {
+	var texts []string
+	for _, req := range batch {
+		texts = append(texts, req.texts...)
+	}
+
+	// The batch outlives any single caller, so it doesn't use their contexts;
+	// the provider's HTTP client timeout bounds it instead
+	vectors, err := b.inner.Embed(context.Background(), texts)
+
+	offset := 0
+	for _, req := range batch {
+		if err != nil {
+			req.result <- batchResult{err: err}
+			continue
+		}
+		req.result <- batchResult{vectors: vectors[offset : offset+len(req.texts)]}
+		offset += len(req.texts)
+	}
+}
+
+func (b *Batcher) Describe() map[string]interface{} {
+	info := Describe(b.inner)
+	info["batch_size"] = b.maxBatch
+	info["batch_wait_ms"] = b.maxWait.Milliseconds()
+	return info
+}
@@ -0,0 +1,91 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Embedder turns texts into vectors, one vector per text in the same order.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// Describer is implemented by embedders that can report what they are, for
+// cache statistics.
+type Describer interface {
+	Describe() map[string]interface{}
+}
+
+// Describe reports what e is, or just that it's unknown.
+func Describe(e Embedder) map[string]interface{} {
+	if d, ok := e.(Describer); ok {
+		return d.Describe()
+	}
+	return map[string]interface{}{"provider": "unknown"}
+}
+
+// Options selects and tunes the embedding provider.
+type Options struct {
+	Provider  string // "flask", "openai", "ollama" or "local"
+	URL       string // base URL of the provider
+	Model     string
+	APIKey    string
+	Timeout   time.Duration
+	BatchSize int           // max texts per upstream call, 0 disables batching
+	BatchWait time.Duration // how long to wait for a batch to fill up
+	CacheSize int           // LRU entries of text hash to vector, 0 disables it
+	Dims      int           // vector size of the local embedder
+}
+
+// New builds the configured provider, wrapped in request batching and an LRU cache.
+func New(opts Options) (Embedder, error) {
+	client := &http.Client{Timeout: opts.Timeout}
+
+	var e Embedder
+	switch opts.Provider {
+	case "", "flask":
+		e = NewFlask(opts.URL, client)
+	case "openai":
+		e = NewOpenAI(opts.URL, opts.APIKey, opts.Model, client)
+	case "ollama":
+		e = NewOllama(opts.URL, opts.Model, client)
+	case "local":
+		// Cheap enough to compute inline, batching and caching only add overhead
+		return NewLocal(opts.Dims), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q", opts.Provider)
+	}
+
+	if opts.BatchSize > 1 {
+		e = NewBatcher(e, opts.BatchSize, opts.BatchWait)
+	}
+	if opts.CacheSize > 0 {
+		e = NewCached(e, opts.CacheSize)
+	}
+
+	return e, nil
+}
+
+// EmbedOne embeds a single text.
+func EmbedOne(ctx context.Context, e Embedder, text string) ([]float64, error) {
+	vectors, err := e.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// checkVectors makes sure a provider answered with one non-empty vector per text.
+func checkVectors(vectors [][]float64, texts []string) error {
+	if len(vectors) != len(texts) {
+		return fmt.Errorf("expected %d embeddings, got %d", len(texts), len(vectors))
+	}
+	for _, v := range vectors {
+		if len(v) == 0 {
+			return fmt.Errorf("empty embedding returned")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// Flask talks to the bundled embedding_service (POST /embed). Batches are
+// sent as {"texts": [...]}; services that predate that form and only take
+// {"text": "..."} get one call per text instead.
+type Flask struct {
+	url    string
+	client *http.Client
+
+	// Set once the service answered a batch as a single-text service would
+	singleOnly atomic.Bool
+}
+
+func NewFlask(url string, client *http.Client) *Flask {
+	return &Flask{url: strings.TrimSuffix(url, "/"), client: client}
+}
+
+func (f *Flask) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if f.singleOnly.Load() {
+		return f.embedEach(ctx, texts)
+	}
+
+	var result struct {
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+	err := postJSON(ctx, f.client, f.url+"/embed", nil, map[string][]string{"texts": texts}, &result)
+
+	var status *statusError
+	if errors.As(err, &status) || (err == nil && result.Embeddings == nil) {
+		reason := "no \"embeddings\" field"
+		if err != nil {
+			reason = err.Error()
+		}
+		embeddings, eachErr := f.embedEach(ctx, texts)
+		if eachErr != nil {
+			log.Printf("❌ Embedding service at %s rejected a batch (%s) and single texts (%v)", f.url, reason, eachErr)
+			return nil, fmt.Errorf("batch embedding failed (%s), then single text embedding failed: %w", reason, eachErr)
+		}
+		// A server error may be passing, so only an answer that doesn't
+		// understand batches switches to single texts for good
+		if (err == nil || status.code < 500) && f.singleOnly.CompareAndSwap(false, true) {
+			log.Printf("⚠️  Embedding service at %s doesn't take batches (%s), embedding one text per call", f.url, reason)
+		}
+		return embeddings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVectors(result.Embeddings, texts); err != nil {
+		return nil, err
+	}
+	return result.Embeddings, nil
+}
+
+// embedEach embeds texts one {"text": "..."} call at a time.
+func (f *Flask) embedEach(ctx context.Context, texts []string) ([][]float64, error) {
+	embeddings := make([][]float64, 0, len(texts))
+	for _, text := range texts {
+		var result struct {
+			Embedding []float64 `json:"embedding"`
+		}
+		if err := postJSON(ctx, f.client, f.url+"/embed", nil, map[string]string{"text": text}, &result); err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, result.Embedding)
+	}
+
+	if err := checkVectors(embeddings, texts); err != nil {
+		return nil, err
+	}
+	return embeddings, nil
+}
+
+func (f *Flask) Describe() map[string]interface{} {
+	return map[string]interface{}{"provider": "flask", "url": f.url}
+}
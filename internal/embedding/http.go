@@ -0,0 +1,59 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// statusError is an answer other than 200 OK.
+type statusError struct {
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("embedding service returned status %d: %s", e.code, e.body)
+}
+
+// postJSON sends body to url and decodes the JSON answer into out.
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, body, out interface{}) error {
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("embedding service request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read embedding response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{code: resp.StatusCode, body: strings.TrimSpace(string(respBody))}
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse embedding response: %w", err)
+	}
+
+	return nil
+}
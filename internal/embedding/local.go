@@ -0,0 +1,95 @@
+package embedding
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Local is a dependency-free embedder built on feature hashing: word
+// unigrams, word bigrams and character trigrams are hashed into a fixed
+// number of signed buckets and the vector is L2-normalized.
+//
+// It captures lexical overlap (shared words, spelling variants, word order)
+// but not meaning, so paraphrases with different vocabulary score low. It's
+// meant for minimal deployments and CI where the embedding service isn't
+// running; pair it with a higher similarity threshold.
+type Local struct {
+	dims int
+}
+
+func NewLocal(dims int) *Local {
+	if dims <= 0 {
+		dims = 512
+	}
+	return &Local{dims: dims}
+}
+
+func (l *Local) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i] = l.vector(text)
+	}
+	return vectors, nil
+}
+
+func (l *Local) Describe() map[string]interface{} {
+	return map[string]interface{}{
+		"provider":   "local",
+		"dimensions": l.dims,
+		"method":     "hashed word unigrams, word bigrams and character trigrams",
+		"quality":    "lexical similarity only; paraphrases with different wording are missed, consider a similarity threshold of 0.9 or higher",
+	}
+}
+
+func (l *Local) vector(text string) []float64 {
+	v := make([]float64, l.dims)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	for i, word := range words {
+		l.add(v, "w:"+word, 1.0)
+		if i > 0 {
+			l.add(v, "b:"+words[i-1]+" "+word, 0.5)
+		}
+
+		padded := []rune("^" + word + "$")
+		for j := 0; j+3 <= len(padded); j++ {
+			l.add(v, "c:"+string(padded[j:j+3]), 0.25)
+		}
+	}
+
+	// Normalize so that cosine similarity only depends on direction
+	var norm float64
+	for _, x := range v {
+		norm += x * x
+	}
+	if norm == 0 {
+		// Never return a zero vector, it has no direction to compare
+		v[0] = 1
+		return v
+	}
+	norm = math.Sqrt(norm)
+	for i := range v {
+		v[i] /= norm
+	}
+
+	return v
+}
+
+// add hashes a feature into a bucket, using one hash bit as the sign so that
+// collisions cancel out on average
+func (l *Local) add(v []float64, feature string, weight float64) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+
+	if sum&(1<<63) != 0 {
+		weight = -weight
+	}
+	v[sum%uint64(l.dims)] += weight
+}
@@ -0,0 +1,112 @@
+package embedding
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"sync"
+)
+
+// Cached keeps the vectors of recently embedded texts, keyed by text hash, so
+// that a prompt embedded on lookup isn't embedded again when it's stored.
+type Cached struct {
+	inner Embedder
+	size  int
+
+	mu     sync.Mutex
+	order  *list.List // front is most recently used
+	items  map[[32]byte]*list.Element
+	hits   int64
+	misses int64
+}
+
+type cachedVector struct {
+	key    [32]byte
+	vector []float64
+}
+
+func NewCached(inner Embedder, size int) *Cached {
+	return &Cached{
+		inner: inner,
+		size:  size,
+		order: list.New(),
+		items: make(map[[32]byte]*list.Element),
+	}
+}
+
+func (c *Cached) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	keys := make([][32]byte, len(texts))
+
+	// Collect the distinct texts we don't have yet
+	var missing []string
+	missingIdx := map[[32]byte]int{}
+
+	c.mu.Lock()
+	for i, text := range texts {
+		keys[i] = sha256.Sum256([]byte(text))
+		if el, ok := c.items[keys[i]]; ok {
+			c.order.MoveToFront(el)
+			vectors[i] = el.Value.(*cachedVector).vector
+			c.hits++
+			continue
+		}
+		c.misses++
+		if _, ok := missingIdx[keys[i]]; !ok {
+			missingIdx[keys[i]] = len(missing)
+			missing = append(missing, text)
+		}
+	}
+	c.mu.Unlock()
+
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	fetched, err := c.inner.Embed(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	for i := range texts {
+		if vectors[i] != nil {
+			continue
+		}
+		vectors[i] = fetched[missingIdx[keys[i]]]
+		c.add(keys[i], vectors[i])
+	}
+	c.mu.Unlock()
+
+	return vectors, nil
+}
+
+// add stores a vector and evicts the least recently used one if full, c.mu must be held
+func (c *Cached) add(key [32]byte, vector []float64) {
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&cachedVector{key: key, vector: vector})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cachedVector).key)
+	}
+}
+
+func (c *Cached) Describe() map[string]interface{} {
+	c.mu.Lock()
+	lru := map[string]interface{}{
+		"capacity": c.size,
+		"entries":  c.order.Len(),
+		"hits":     c.hits,
+		"misses":   c.misses,
+	}
+	c.mu.Unlock()
+
+	info := Describe(c.inner)
+	info["lru"] = lru
+	return info
+}
@@ -0,0 +1,44 @@
+package embedding
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Ollama talks to an Ollama-style POST /api/embed endpoint.
+type Ollama struct {
+	url    string
+	model  string
+	client *http.Client
+}
+
+func NewOllama(url, model string, client *http.Client) *Ollama {
+	if model == "" {
+		model = "all-minilm"
+	}
+	return &Ollama{url: strings.TrimSuffix(url, "/"), model: model, client: client}
+}
+
+func (o *Ollama) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	var result struct {
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+
+	err := postJSON(ctx, o.client, o.url+"/api/embed", nil, map[string]interface{}{
+		"model": o.model,
+		"input": texts,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVectors(result.Embeddings, texts); err != nil {
+		return nil, err
+	}
+	return result.Embeddings, nil
+}
+
+func (o *Ollama) Describe() map[string]interface{} {
+	return map[string]interface{}{"provider": "ollama", "url": o.url, "model": o.model}
+}
@@ -0,0 +1,61 @@
+package embedding
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// OpenAI talks to any OpenAI-compatible POST /v1/embeddings endpoint.
+type OpenAI struct {
+	url    string
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+func NewOpenAI(url, apiKey, model string, client *http.Client) *OpenAI {
+	if model == "" {
+		model = "text-embedding-3-small"
+	}
+	return &OpenAI{url: strings.TrimSuffix(url, "/"), apiKey: apiKey, model: model, client: client}
+}
+
+func (o *OpenAI) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	header := http.Header{}
+	if o.apiKey != "" {
+		header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+
+	err := postJSON(ctx, o.client, o.url+"/v1/embeddings", header, map[string]interface{}{
+		"model": o.model,
+		"input": texts,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	// The API doesn't promise to keep input order, every item carries its index
+	vectors := make([][]float64, len(texts))
+	for _, item := range result.Data {
+		if item.Index >= 0 && item.Index < len(vectors) {
+			vectors[item.Index] = item.Embedding
+		}
+	}
+
+	if err := checkVectors(vectors, texts); err != nil {
+		return nil, err
+	}
+	return vectors, nil
+}
+
+func (o *OpenAI) Describe() map[string]interface{} {
+	return map[string]interface{}{"provider": "openai", "url": o.url, "model": o.model}
+}
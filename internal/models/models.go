@@ -8,10 +8,77 @@ type Tenant struct {
 	APIKey           string    `json:"api_key"`
 	RateLimitPerHour int       `json:"rate_limit_per_hour"`
 	BackendURL       string    `json:"backend_url"`
+	LBStrategy       string    `json:"lb_strategy"`
+	LBHashHeader     string    `json:"lb_hash_header,omitempty"`
+	APIFormat        string    `json:"api_format"`
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
 }
 
+// TenantBackend is one backend in a tenant's load-balanced pool.
+type TenantBackend struct {
+	ID              int       `json:"id"`
+	TenantID        int       `json:"tenant_id"`
+	URL             string    `json:"url"`
+	Weight          int       `json:"weight"`
+	Enabled         bool      `json:"enabled"`
+	HealthCheckPath string    `json:"health_check_path,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TenantFallback is one step of a tenant's fallback chain.
+type TenantFallback struct {
+	ID        int       `json:"id"`
+	TenantID  int       `json:"tenant_id"`
+	Position  int       `json:"position"`
+	Provider  string    `json:"provider"`
+	URL       string    `json:"url"`
+	Model     string    `json:"model,omitempty"`
+	APIFormat string    `json:"api_format"`
+	TimeoutMs int       `json:"timeout_ms"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TenantModelPolicy restricts the models a tenant's LLM requests may ask
+// for and routes some of them to backends of their own. Model patterns are
+// names, or prefixes ending in *, matched case-insensitively.
+type TenantModelPolicy struct {
+	TenantID      int               `json:"tenant_id"`
+	AllowedModels []string          `json:"allowed_models"` // empty allows every model not denied
+	DeniedModels  []string          `json:"denied_models"`
+	Aliases       map[string]string `json:"aliases"` // e.g. "fast" -> "gpt-4o-mini"
+	Routes        []ModelRoute      `json:"routes"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// ModelRoute sends requests for matching models to a backend instead of the
+// tenant's pool. The tenant's fallback chain still applies.
+type ModelRoute struct {
+	Model     string `json:"model"`
+	URL       string `json:"url"`
+	APIFormat string `json:"api_format,omitempty"` // defaults to the tenant's
+}
+
+// TenantCredential is a provider credential the gateway adds to a tenant's
+// upstream requests. The secret is stored sealed and never returned.
+type TenantCredential struct {
+	ID           int       `json:"id"`
+	TenantID     int       `json:"tenant_id"`
+	Name         string    `json:"name"`
+	BackendURL   string    `json:"backend_url,omitempty"` // empty applies to every backend
+	Location     string    `json:"location"`              // header or query
+	Param        string    `json:"param"`                 // header or query parameter name
+	Prefix       string    `json:"prefix,omitempty"`      // e.g. "Bearer "
+	WrappedKey   []byte    `json:"-"`
+	SealedSecret []byte    `json:"-"`
+	SecretHint   string    `json:"secret_hint"`
+	Version      int       `json:"version"`
+	CreatedAt    time.Time `json:"created_at"`
+	RotatedAt    time.Time `json:"rotated_at"`
+}
+
 type AccessLog struct {
 	ID             int64     `json:"id"`
 	TenantID       int       `json:"tenant_id"`
@@ -21,17 +88,127 @@ type AccessLog struct {
 	ResponseTimeMs int       `json:"response_time_ms"`
 	RequestSize    int64     `json:"request_size"`
 	ResponseSize   int64     `json:"response_size"`
+	Backend        string    `json:"backend,omitempty"`
+	Provider       string    `json:"provider,omitempty"`
+	FallbackStep   *int      `json:"fallback_step,omitempty"` // nil unless a fallback answered
+	TTFBMs         *int      `json:"ttfb_ms"`                 // nil for requests answered without the backend
 	Timestamp      time.Time `json:"timestamp"`
 }
 
 type SemanticCache struct {
-	ID              int64     `json:"id"`
-	TenantID        int       `json:"tenant_id"`
-	PromptHash      string    `json:"prompt_hash"`
-	Prompt          string    `json:"prompt"`
-	Response        string    `json:"response"`
-	EmbeddingStored bool      `json:"embedding_stored"`
-	HitCount        int       `json:"hit_count"`
-	CreatedAt       time.Time `json:"created_at"`
-	LastAccessed    time.Time `json:"last_accessed"`
+	ID               int64             `json:"id"`
+	TenantID         int               `json:"tenant_id"`
+	PromptHash       string            `json:"prompt_hash"`
+	Model            string            `json:"model"` // the model that answered, part of the prompt hash
+	Prompt           string            `json:"prompt"`
+	NormalizedPrompt string            `json:"normalized_prompt"`
+	Response         string            `json:"response"`
+	StatusCode       int               `json:"status_code"`
+	ResponseHeaders  map[string]string `json:"response_headers"`
+	ContentEncoding  string            `json:"content_encoding"` // how the upstream body was encoded, Response is decoded
+	EmbeddingStored  bool              `json:"embedding_stored"`
+	DataKeyVersion   *int              `json:"data_key_version,omitempty"` // set while the row is encrypted
+	Compression      string            `json:"compression,omitempty"`
+	NamespaceID      *int              `json:"namespace_id,omitempty"` // shared entries have no tenant
+	SourceTenantID   *int              `json:"source_tenant_id,omitempty"`
+	HitCount         int               `json:"hit_count"`
+	CreatedAt        time.Time         `json:"created_at"`
+	LastAccessed     time.Time         `json:"last_accessed"`
+	ExpiresAt        *time.Time        `json:"expires_at"`
+}
+
+// CacheEntryRef identifies a cache row, e.g. one removed by expiry or eviction.
+type CacheEntryRef struct {
+	TenantID    int
+	PromptHash  string
+	Model       string
+	NamespaceID int // non-zero for shared entries, which have no tenant
+}
+
+// TenantCacheSettings overrides the gateway cache defaults for one tenant.
+// A nil field means "use the default".
+type TenantCacheSettings struct {
+	TenantID       int     `json:"tenant_id"`
+	TTLSeconds     *int    `json:"ttl_seconds"`
+	SoftTTLSeconds *int    `json:"soft_ttl_seconds"`
+	MaxEntries     *int    `json:"max_entries"`
+	EvictionPolicy *string `json:"eviction_policy"`
+	EncryptAtRest  *bool   `json:"encrypt_at_rest"`
+	SharedCache    *bool   `json:"shared_cache"` // opt in to the namespaces the tenant is a member of
+
+	// Minimum similarity for a semantic hit, raised automatically by feedback
+	SimilarityThreshold *float64   `json:"similarity_threshold"`
+	ThresholdAdjustedAt *time.Time `json:"threshold_adjusted_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+
+	// Applied after the gateway-wide normalization chain
+	NormalizationRules []NormalizationRule `json:"normalization_rules"`
+}
+
+// NormalizationRule is a per-tenant regex rewrite applied to prompts before
+// they're hashed and embedded.
+type NormalizationRule struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// DataKey is a tenant's cache encryption key, wrapped by the master key.
+type DataKey struct {
+	TenantID   int       `json:"tenant_id"`
+	Version    int       `json:"version"`
+	WrappedKey []byte    `json:"-"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CircuitBreakerEvent records a backend's circuit breaker changing state.
+type CircuitBreakerEvent struct {
+	ID        int64     `json:"id"`
+	Backend   string    `json:"backend"`
+	FromState string    `json:"from_state"`
+	ToState   string    `json:"to_state"`
+	Reason    string    `json:"reason"`
+	Instance  string    `json:"instance,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CacheHitEvent records one cache hit and any feedback on it.
+type CacheHitEvent struct {
+	ID              int64   `json:"id"`
+	TenantID        int     `json:"tenant_id"`
+	EntryID         *int64  `json:"entry_id"`
+	PromptHash      string  `json:"prompt_hash"`
+	EntryPromptHash string  `json:"entry_prompt_hash"`
+	Similarity      float64 `json:"similarity"`
+	Semantic        bool    `json:"semantic"`
+	NamespaceID     *int    `json:"namespace_id"`
+
+	// What the hit saved, from the cached response's usage
+	Model            string   `json:"model,omitempty"`
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	CostSaved        *float64 `json:"cost_saved_usd"` // nil when the model has no known price
+
+	Feedback   *string    `json:"feedback"`
+	FeedbackAt *time.Time `json:"feedback_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CacheNamespace is a cache shared by the tenants that are members of it.
+type CacheNamespace struct {
+	ID          int                    `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	CreatedAt   time.Time              `json:"created_at"`
+	Members     []CacheNamespaceMember `json:"members,omitempty"`
+}
+
+// CacheNamespaceMember says what a tenant may do with a namespace.
+type CacheNamespaceMember struct {
+	NamespaceID int       `json:"namespace_id"`
+	Namespace   string    `json:"namespace,omitempty"`
+	TenantID    int       `json:"tenant_id"`
+	CanRead     bool      `json:"can_read"`
+	CanWrite    bool      `json:"can_write"`
+	CreatedAt   time.Time `json:"created_at"`
 }
@@ -0,0 +1,124 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Price is what a model charges, in US dollars per million tokens.
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// Table maps model names to prices.
+type Table map[string]Price
+
+// List prices at the time of writing; override or extend them with MODEL_PRICES.
+var defaultPrices = Table{
+	"gpt-4o":            {Input: 2.50, Output: 10.00},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.60},
+	"gpt-4-turbo":       {Input: 10.00, Output: 30.00},
+	"gpt-4":             {Input: 30.00, Output: 60.00},
+	"gpt-3.5-turbo":     {Input: 0.50, Output: 1.50},
+	"claude-3-5-sonnet": {Input: 3.00, Output: 15.00},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4.00},
+	"claude-3-opus":     {Input: 15.00, Output: 75.00},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25},
+}
+
+// Parse reads comma-separated "model=input:output" prices on top of the
+// defaults, e.g. "gpt-4o=2.5:10,my-model=1:2".
+func Parse(spec string) (Table, error) {
+	table := make(Table, len(defaultPrices))
+	for model, price := range defaultPrices {
+		table[model] = price
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		model, prices, ok := strings.Cut(entry, "=")
+		input, output, ok2 := strings.Cut(prices, ":")
+		if !ok || !ok2 || strings.TrimSpace(model) == "" {
+			return nil, fmt.Errorf("invalid model price %q, want model=input:output", entry)
+		}
+
+		var price Price
+		var err error
+		if price.Input, err = strconv.ParseFloat(strings.TrimSpace(input), 64); err != nil || price.Input < 0 {
+			return nil, fmt.Errorf("invalid input price in %q", entry)
+		}
+		if price.Output, err = strconv.ParseFloat(strings.TrimSpace(output), 64); err != nil || price.Output < 0 {
+			return nil, fmt.Errorf("invalid output price in %q", entry)
+		}
+		table[strings.ToLower(strings.TrimSpace(model))] = price
+	}
+
+	return table, nil
+}
+
+// Lookup finds a model's price. Models without an entry of their own use the
+// longest entry they start with, so dated snapshots like gpt-4o-2024-08-06
+// are priced as gpt-4o.
+func (t Table) Lookup(model string) (Price, bool) {
+	model = strings.ToLower(model)
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+
+	var best string
+	for name := range t {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return t[best], true
+}
+
+// Usage is the token usage a backend reported for one response.
+type Usage struct {
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// ParseUsage reads the model and token usage from an OpenAI- or
+// Anthropic-style response body.
+func ParseUsage(body []byte) (Usage, bool) {
+	var resp struct {
+		Model string `json:"model"`
+		Usage *struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			InputTokens      int `json:"input_tokens"`
+			OutputTokens     int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Usage == nil {
+		return Usage{}, false
+	}
+
+	return Usage{
+		Model:            resp.Model,
+		PromptTokens:     resp.Usage.PromptTokens + resp.Usage.InputTokens,
+		CompletionTokens: resp.Usage.CompletionTokens + resp.Usage.OutputTokens,
+	}, true
+}
+
+// Cost estimates what a response with this usage cost, in US dollars.
+func (t Table) Cost(usage Usage) (float64, bool) {
+	price, ok := t.Lookup(usage.Model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(usage.PromptTokens)*price.Input + float64(usage.CompletionTokens)*price.Output) / 1e6, true
+}
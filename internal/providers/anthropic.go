@@ -0,0 +1,276 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const anthropicVersion = "2023-06-01"
+
+// Anthropic requires max_tokens; used when the client didn't set one
+const anthropicDefaultMaxTokens = 4096
+
+type anthropicTranslator struct {
+	model string
+}
+
+type anthropicBlock struct {
+	Type   string                `json:"type"`
+	Text   string                `json:"text,omitempty"`
+	Source *anthropicImageSource `json:"source,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	System        string             `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	MaxTokens     int                `json:"max_tokens"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+	Metadata      *struct {
+		UserID string `json:"user_id"`
+	} `json:"metadata,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	ID         string           `json:"id"`
+	Model      string           `json:"model"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      anthropicUsage   `json:"usage"`
+}
+
+func (t *anthropicTranslator) Request(path string, body []byte) (*Request, error) {
+	prefix, ok := chatPath(path)
+	if !ok {
+		return nil, nil
+	}
+	req, err := parseChatRequest(Anthropic, body)
+	if err != nil {
+		return nil, err
+	}
+	if req.N != nil && *req.N > 1 {
+		return nil, unsupported(Anthropic, "n > 1 is")
+	}
+	t.model = req.Model
+
+	out := anthropicRequest{
+		Model:         req.Model,
+		MaxTokens:     anthropicDefaultMaxTokens,
+		TopP:          req.TopP,
+		StopSequences: req.stop(),
+		Stream:        req.Stream,
+	}
+	if maxTokens := req.maxTokens(); maxTokens != nil {
+		out.MaxTokens = *maxTokens
+	}
+	if req.Temperature != nil {
+		// OpenAI goes up to 2, Anthropic up to 1
+		temperature := min(*req.Temperature, 1)
+		out.Temperature = &temperature
+	}
+	if req.User != "" {
+		out.Metadata = &struct {
+			UserID string `json:"user_id"`
+		}{UserID: req.User}
+	}
+
+	var system []string
+	for _, m := range req.Messages {
+		switch m.Role {
+		case "system", "developer":
+			text, err := m.text()
+			if err != nil {
+				return nil, err
+			}
+			system = append(system, text)
+		case "user", "assistant":
+			blocks, err := anthropicBlocks(m)
+			if err != nil {
+				return nil, err
+			}
+			// Anthropic wants roles to alternate, so consecutive messages
+			// of one role are merged
+			if n := len(out.Messages); n > 0 && out.Messages[n-1].Role == m.Role {
+				out.Messages[n-1].Content = append(out.Messages[n-1].Content, blocks...)
+			} else {
+				out.Messages = append(out.Messages, anthropicMessage{Role: m.Role, Content: blocks})
+			}
+		default:
+			return nil, unsupported(Anthropic, fmt.Sprintf("%q messages are", m.Role))
+		}
+	}
+	out.System = strings.Join(system, "\n\n")
+	if len(out.Messages) == 0 {
+		return nil, fmt.Errorf("messages must include a user message")
+	}
+
+	translated, err := json.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set("anthropic-version", anthropicVersion)
+	return &Request{Path: prefix + "/v1/messages", Header: header, Body: translated}, nil
+}
+
+func anthropicBlocks(m chatMessage) ([]anthropicBlock, error) {
+	parts, err := m.parts()
+	if err != nil {
+		return nil, err
+	}
+	blocks := make([]anthropicBlock, 0, len(parts))
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			blocks = append(blocks, anthropicBlock{Type: "text", Text: p.Text})
+		case "image_url":
+			if p.ImageURL == nil {
+				return nil, fmt.Errorf("image_url part without a url")
+			}
+			source := &anthropicImageSource{Type: "url", URL: p.ImageURL.URL}
+			if mediaType, data, ok := parseDataURL(p.ImageURL.URL); ok {
+				source = &anthropicImageSource{Type: "base64", MediaType: mediaType, Data: data}
+			}
+			blocks = append(blocks, anthropicBlock{Type: "image", Source: source})
+		default:
+			return nil, unsupported(Anthropic, fmt.Sprintf("%q content is", p.Type))
+		}
+	}
+	return blocks, nil
+}
+
+func anthropicFinishReason(reason string) *string {
+	var finish string
+	switch reason {
+	case "":
+		return nil
+	case "max_tokens":
+		finish = "length"
+	case "tool_use":
+		finish = "tool_calls"
+	case "refusal":
+		finish = "content_filter"
+	default: // end_turn, stop_sequence
+		finish = "stop"
+	}
+	return &finish
+}
+
+func (t *anthropicTranslator) Response(body []byte) ([]byte, error) {
+	var resp anthropicResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid Anthropic response: %w", err)
+	}
+
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	model := resp.Model
+	if model == "" {
+		model = t.model
+	}
+
+	return json.Marshal(chatCompletion{
+		ID:      completionID(strings.TrimPrefix(resp.ID, "msg_")),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []chatChoice{{
+			Message:      &chatOutput{Role: "assistant", Content: text.String()},
+			FinishReason: anthropicFinishReason(resp.StopReason),
+		}},
+		Usage: newUsage(resp.Usage.InputTokens, resp.Usage.OutputTokens),
+	})
+}
+
+func (t *anthropicTranslator) Stream(src io.Reader, dst io.Writer) error {
+	out := newChunkWriter(dst, t.model)
+	var usage anthropicUsage
+
+	return readEvents(src, func(_, data string) error {
+		var event struct {
+			Type    string             `json:"type"`
+			Message *anthropicResponse `json:"message"`
+			Delta   struct {
+				Type       string `json:"type"`
+				Text       string `json:"text"`
+				StopReason string `json:"stop_reason"`
+			} `json:"delta"`
+			Usage *anthropicUsage `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				if event.Message.Model != "" {
+					out.model = event.Message.Model
+				}
+				usage.InputTokens = event.Message.Usage.InputTokens
+			}
+			return out.delta(0, chatDelta{Role: "assistant"}, nil)
+		case "content_block_delta":
+			if event.Delta.Type != "text_delta" {
+				return nil
+			}
+			return out.delta(0, chatDelta{Content: event.Delta.Text}, nil)
+		case "message_delta":
+			if event.Usage != nil {
+				usage.OutputTokens = event.Usage.OutputTokens
+			}
+			if finish := anthropicFinishReason(event.Delta.StopReason); finish != nil {
+				return out.delta(0, chatDelta{}, finish)
+			}
+		case "message_stop":
+			if err := out.chunk([]chatChoice{}, newUsage(usage.InputTokens, usage.OutputTokens)); err != nil {
+				return err
+			}
+			return out.done()
+		case "error":
+			return out.send(t.Error([]byte(data)))
+		}
+		return nil
+	})
+}
+
+func (t *anthropicTranslator) Error(body []byte) []byte {
+	var resp struct {
+		Error *struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Error == nil {
+		return body
+	}
+	return openAIError(resp.Error.Message, resp.Error.Type, nil)
+}
@@ -0,0 +1,280 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type geminiTranslator struct {
+	model string
+}
+
+type geminiPart struct {
+	Text       string      `json:"text,omitempty"`
+	InlineData *geminiBlob `json:"inlineData,omitempty"`
+}
+
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	MaxOutputTokens *int     `json:"maxOutputTokens,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+	CandidateCount  *int     `json:"candidateCount,omitempty"`
+}
+
+type geminiRequest struct {
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Index        int           `json:"index"`
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+	ModelVersion string `json:"modelVersion"`
+	ResponseID   string `json:"responseId"`
+}
+
+func (t *geminiTranslator) Request(path string, body []byte) (*Request, error) {
+	prefix, ok := chatPath(path)
+	if !ok {
+		return nil, nil
+	}
+	req, err := parseChatRequest(Gemini, body)
+	if err != nil {
+		return nil, err
+	}
+	t.model = req.Model
+
+	out := geminiRequest{
+		GenerationConfig: &geminiGenerationConfig{
+			Temperature:     req.Temperature,
+			TopP:            req.TopP,
+			MaxOutputTokens: req.maxTokens(),
+			StopSequences:   req.stop(),
+			CandidateCount:  req.N,
+		},
+	}
+
+	var system []geminiPart
+	for _, m := range req.Messages {
+		switch m.Role {
+		case "system", "developer":
+			text, err := m.text()
+			if err != nil {
+				return nil, err
+			}
+			system = append(system, geminiPart{Text: text})
+		case "user", "assistant":
+			parts, err := geminiParts(m)
+			if err != nil {
+				return nil, err
+			}
+			role := "user"
+			if m.Role == "assistant" {
+				role = "model"
+			}
+			out.Contents = append(out.Contents, geminiContent{Role: role, Parts: parts})
+		default:
+			return nil, unsupported(Gemini, fmt.Sprintf("%q messages are", m.Role))
+		}
+	}
+	if len(system) > 0 {
+		out.SystemInstruction = &geminiContent{Parts: system}
+	}
+	if len(out.Contents) == 0 {
+		return nil, fmt.Errorf("messages must include a user message")
+	}
+
+	translated, err := json.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+	translatedPath := prefix + "/v1beta/models/" + url.PathEscape(req.Model) + ":generateContent"
+	if req.Stream {
+		translatedPath = prefix + "/v1beta/models/" + url.PathEscape(req.Model) + ":streamGenerateContent"
+		return &Request{Path: translatedPath, RawQuery: "alt=sse", Body: translated}, nil
+	}
+	return &Request{Path: translatedPath, Body: translated}, nil
+}
+
+func geminiParts(m chatMessage) ([]geminiPart, error) {
+	parts, err := m.parts()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]geminiPart, 0, len(parts))
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			out = append(out, geminiPart{Text: p.Text})
+		case "image_url":
+			if p.ImageURL == nil {
+				return nil, fmt.Errorf("image_url part without a url")
+			}
+			mediaType, data, ok := parseDataURL(p.ImageURL.URL)
+			if !ok {
+				return nil, unsupported(Gemini, "image URLs other than base64 data URLs are")
+			}
+			out = append(out, geminiPart{InlineData: &geminiBlob{MimeType: mediaType, Data: data}})
+		default:
+			return nil, unsupported(Gemini, fmt.Sprintf("%q content is", p.Type))
+		}
+	}
+	return out, nil
+}
+
+func geminiFinishReason(reason string) *string {
+	var finish string
+	switch reason {
+	case "":
+		return nil
+	case "STOP":
+		finish = "stop"
+	case "MAX_TOKENS":
+		finish = "length"
+	default: // SAFETY, RECITATION, BLOCKLIST, PROHIBITED_CONTENT...
+		finish = "content_filter"
+	}
+	return &finish
+}
+
+func (t *geminiTranslator) choices(resp *geminiResponse, stream bool) []chatChoice {
+	choices := make([]chatChoice, 0, len(resp.Candidates))
+	for _, c := range resp.Candidates {
+		var text strings.Builder
+		for _, p := range c.Content.Parts {
+			text.WriteString(p.Text)
+		}
+		choice := chatChoice{Index: c.Index, FinishReason: geminiFinishReason(c.FinishReason)}
+		if stream {
+			choice.Delta = &chatDelta{Content: text.String()}
+		} else {
+			choice.Message = &chatOutput{Role: "assistant", Content: text.String()}
+		}
+		choices = append(choices, choice)
+	}
+	return choices
+}
+
+func (r *geminiResponse) usage() *chatUsage {
+	if r.UsageMetadata == nil {
+		return nil
+	}
+	return newUsage(r.UsageMetadata.PromptTokenCount, r.UsageMetadata.CandidatesTokenCount)
+}
+
+func (t *geminiTranslator) Response(body []byte) ([]byte, error) {
+	var resp geminiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid Gemini response: %w", err)
+	}
+	model := resp.ModelVersion
+	if model == "" {
+		model = t.model
+	}
+
+	return json.Marshal(chatCompletion{
+		ID:      completionID(resp.ResponseID),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: t.choices(&resp, false),
+		Usage:   resp.usage(),
+	})
+}
+
+func (t *geminiTranslator) Stream(src io.Reader, dst io.Writer) error {
+	out := newChunkWriter(dst, t.model)
+	var usage *chatUsage
+	first := true
+
+	err := readEvents(src, func(_, data string) error {
+		if isGeminiError(data) {
+			return out.send(t.Error([]byte(data)))
+		}
+		var resp geminiResponse
+		if err := json.Unmarshal([]byte(data), &resp); err != nil {
+			return nil
+		}
+		if resp.ModelVersion != "" {
+			out.model = resp.ModelVersion
+		}
+		if u := resp.usage(); u != nil {
+			usage = u // cumulative, the last one counts
+		}
+
+		choices := t.choices(&resp, true)
+		if first {
+			for i := range choices {
+				choices[i].Delta.Role = "assistant"
+			}
+			first = false
+		}
+		if len(choices) == 0 {
+			return nil
+		}
+		return out.chunk(choices, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	if usage != nil {
+		if err := out.chunk([]chatChoice{}, usage); err != nil {
+			return err
+		}
+	}
+	return out.done()
+}
+
+func isGeminiError(data string) bool {
+	var resp struct {
+		Error json.RawMessage `json:"error"`
+	}
+	return json.Unmarshal([]byte(data), &resp) == nil && isSet(resp.Error)
+}
+
+func (t *geminiTranslator) Error(body []byte) []byte {
+	// Errors come as an object, or a list of one
+	body = []byte(strings.TrimSpace(string(body)))
+	if len(body) > 0 && body[0] == '[' {
+		var list []json.RawMessage
+		if json.Unmarshal(body, &list) == nil && len(list) == 1 {
+			body = list[0]
+		}
+	}
+
+	var resp struct {
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Status  string `json:"status"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Error == nil {
+		return body
+	}
+	return openAIError(resp.Error.Message, strings.ToLower(resp.Error.Status), resp.Error.Code)
+}
@@ -0,0 +1,386 @@
+package providers
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Format is the API a backend speaks.
+type Format string
+
+const (
+	OpenAI    Format = "openai" // chat completions, also what clients speak
+	Anthropic Format = "anthropic"
+	Gemini    Format = "gemini"
+)
+
+// Valid reports whether f is a known format.
+func (f Format) Valid() bool {
+	switch f {
+	case OpenAI, Anthropic, Gemini:
+		return true
+	}
+	return false
+}
+
+// Request is a client request rewritten for a backend.
+type Request struct {
+	Path     string
+	RawQuery string      // added to the client's query
+	Header   http.Header // set unless the client sent them
+	Body     []byte
+}
+
+// Translator converts one OpenAI chat completions exchange to and from a
+// backend's own format. A Translator is stateful and serves one request.
+type Translator interface {
+	// Request rewrites an OpenAI request. It returns nil for requests that
+	// aren't chat completions, which are forwarded as is.
+	Request(path string, body []byte) (*Request, error)
+	// Response turns a successful response into a chat completion.
+	Response(body []byte) ([]byte, error)
+	// Stream turns a response event stream into OpenAI chunks.
+	Stream(src io.Reader, dst io.Writer) error
+	// Error turns an error response into OpenAI's error shape, or returns
+	// it as is when it isn't recognized.
+	Error(body []byte) []byte
+}
+
+// For returns a new translator for a backend format, or nil if the backend
+// speaks OpenAI's format already.
+func For(f Format) Translator {
+	switch f {
+	case Anthropic:
+		return &anthropicTranslator{}
+	case Gemini:
+		return &geminiTranslator{}
+	}
+	return nil
+}
+
+// ErrUnsupported is wrapped by errors for requests that can't be expressed
+// in a backend's format.
+var ErrUnsupported = errors.New("not supported")
+
+func unsupported(format Format, what string) error {
+	return fmt.Errorf("%s %w by %s backends", what, ErrUnsupported, format)
+}
+
+// ============ OpenAI requests ============
+
+type chatRequest struct {
+	Model               string          `json:"model"`
+	Messages            []chatMessage   `json:"messages"`
+	MaxTokens           *int            `json:"max_tokens"`
+	MaxCompletionTokens *int            `json:"max_completion_tokens"`
+	Temperature         *float64        `json:"temperature"`
+	TopP                *float64        `json:"top_p"`
+	Stop                json.RawMessage `json:"stop"`
+	Stream              bool            `json:"stream"`
+	N                   *int            `json:"n"`
+	User                string          `json:"user"`
+	Tools               json.RawMessage `json:"tools"`
+	Functions           json.RawMessage `json:"functions"`
+}
+
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type contentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url"`
+}
+
+// chatPath reports whether path is a chat completions endpoint, and returns
+// what comes before its version, e.g. "/proxy" for "/proxy/v1/chat/completions".
+func chatPath(path string) (string, bool) {
+	if prefix, ok := strings.CutSuffix(path, "/v1/chat/completions"); ok {
+		return prefix, true
+	}
+	return strings.CutSuffix(path, "/chat/completions")
+}
+
+func parseChatRequest(format Format, body []byte) (*chatRequest, error) {
+	var req chatRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("invalid chat completions request: %w", err)
+	}
+	if req.Model == "" {
+		return nil, errors.New("model is required")
+	}
+	if len(req.Messages) == 0 {
+		return nil, errors.New("messages is required")
+	}
+	if isSet(req.Tools) || isSet(req.Functions) {
+		return nil, unsupported(format, "tool calling is")
+	}
+	return &req, nil
+}
+
+func isSet(raw json.RawMessage) bool {
+	return len(raw) > 0 && string(raw) != "null"
+}
+
+func (req *chatRequest) maxTokens() *int {
+	if req.MaxCompletionTokens != nil {
+		return req.MaxCompletionTokens
+	}
+	return req.MaxTokens
+}
+
+// stop reads "stop", which is a string or a list of them.
+func (req *chatRequest) stop() []string {
+	if !isSet(req.Stop) {
+		return nil
+	}
+	var one string
+	if err := json.Unmarshal(req.Stop, &one); err == nil {
+		return []string{one}
+	}
+	var many []string
+	json.Unmarshal(req.Stop, &many)
+	return many
+}
+
+// parts reads a message's content, which is a string or a list of parts.
+func (m chatMessage) parts() ([]contentPart, error) {
+	if !isSet(m.Content) {
+		return nil, nil
+	}
+	var text string
+	if err := json.Unmarshal(m.Content, &text); err == nil {
+		return []contentPart{{Type: "text", Text: text}}, nil
+	}
+	var parts []contentPart
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return nil, fmt.Errorf("invalid %s message content", m.Role)
+	}
+	return parts, nil
+}
+
+// text joins the text parts of a message.
+func (m chatMessage) text() (string, error) {
+	parts, err := m.parts()
+	if err != nil {
+		return "", err
+	}
+	var texts []string
+	for _, p := range parts {
+		if p.Type == "text" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// parseDataURL splits a base64 data URL into its media type and data.
+func parseDataURL(raw string) (mediaType, data string, ok bool) {
+	rest, ok := strings.CutPrefix(raw, "data:")
+	if !ok {
+		return "", "", false
+	}
+	meta, data, ok := strings.Cut(rest, ",")
+	mediaType, isBase64 := strings.CutSuffix(meta, ";base64")
+	if !ok || !isBase64 {
+		return "", "", false
+	}
+	return mediaType, data, true
+}
+
+// ============ OpenAI responses ============
+
+type chatCompletion struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []chatChoice `json:"choices"`
+	Usage   *chatUsage   `json:"usage,omitempty"`
+}
+
+type chatChoice struct {
+	Index        int         `json:"index"`
+	Message      *chatOutput `json:"message,omitempty"`
+	Delta        *chatDelta  `json:"delta,omitempty"`
+	FinishReason *string     `json:"finish_reason"`
+}
+
+type chatOutput struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatDelta struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+func newUsage(prompt, completion int) *chatUsage {
+	return &chatUsage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+}
+
+func completionID(id string) string {
+	if id == "" {
+		b := make([]byte, 12)
+		rand.Read(b)
+		id = hex.EncodeToString(b)
+	}
+	return "chatcmpl-" + id
+}
+
+// openAIError builds an OpenAI-style error body.
+func openAIError(message, errType string, code interface{}) []byte {
+	body, _ := json.Marshal(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    errType,
+			"param":   nil,
+			"code":    code,
+		},
+	})
+	return body
+}
+
+// chunkWriter writes OpenAI chat completion chunks as server-sent events.
+type chunkWriter struct {
+	dst     io.Writer
+	id      string
+	model   string
+	created int64
+}
+
+func newChunkWriter(dst io.Writer, model string) *chunkWriter {
+	return &chunkWriter{dst: dst, id: completionID(""), model: model, created: time.Now().Unix()}
+}
+
+func (c *chunkWriter) send(data []byte) error {
+	_, err := fmt.Fprintf(c.dst, "data: %s\n\n", data)
+	return err
+}
+
+func (c *chunkWriter) chunk(choices []chatChoice, usage *chatUsage) error {
+	data, err := json.Marshal(chatCompletion{
+		ID:      c.id,
+		Object:  "chat.completion.chunk",
+		Created: c.created,
+		Model:   c.model,
+		Choices: choices,
+		Usage:   usage,
+	})
+	if err != nil {
+		return err
+	}
+	return c.send(data)
+}
+
+func (c *chunkWriter) delta(index int, delta chatDelta, finishReason *string) error {
+	return c.chunk([]chatChoice{{Index: index, Delta: &delta, FinishReason: finishReason}}, nil)
+}
+
+func (c *chunkWriter) done() error {
+	return c.send([]byte("[DONE]"))
+}
+
+// readEvents calls fn with the event name and data of every server-sent event.
+func readEvents(src io.Reader, fn func(event, data string) error) error {
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var event string
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = ""
+			return nil
+		}
+		err := fn(event, strings.Join(data, "\n"))
+		event, data = "", nil
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return dispatch()
+}
+
+// ============ Prompts ============
+
+// LastMessageText returns the text of the last message of an OpenAI,
+// Anthropic or Gemini chat request, or "" if body is none of them. Messages
+// with anything but text in them, such as images, also give "": their text
+// alone doesn't say what was asked, so they must not be cached on it.
+func LastMessageText(body []byte) string {
+	var req struct {
+		Messages []chatMessage `json:"messages"`
+		Contents []struct {
+			Parts []map[string]json.RawMessage `json:"parts"`
+		} `json:"contents"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+
+	// OpenAI and Anthropic: a string or a list of parts/blocks
+	if len(req.Messages) > 0 {
+		parts, err := req.Messages[len(req.Messages)-1].parts()
+		if err != nil {
+			return ""
+		}
+		var texts []string
+		for _, p := range parts {
+			if p.Type != "text" {
+				return ""
+			}
+			texts = append(texts, p.Text)
+		}
+		return strings.Join(texts, "\n")
+	}
+
+	// Gemini
+	if len(req.Contents) > 0 {
+		var texts []string
+		for _, p := range req.Contents[len(req.Contents)-1].Parts {
+			var text string
+			if len(p) != 1 || json.Unmarshal(p["text"], &text) != nil {
+				return ""
+			}
+			texts = append(texts, text)
+		}
+		return strings.Join(texts, "\n")
+	}
+
+	return ""
+}
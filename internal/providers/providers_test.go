@@ -0,0 +1,36 @@
+package providers
+
+import "testing"
+
+func TestLastMessageTextSkipsImages(t *testing.T) {
+	withImage := func(url string) []byte {
+		return []byte(`{"model": "gpt-4o", "messages": [{"role": "user", "content": [
+			{"type": "text", "text": "What is in this picture?"},
+			{"type": "image_url", "image_url": {"url": "` + url + `"}}
+		]}]}`)
+	}
+
+	// Same text, different images: neither may get a cache key of its own text
+	a := LastMessageText(withImage("https://example.com/cat.png"))
+	b := LastMessageText(withImage("https://example.com/dog.png"))
+	if a != "" || b != "" {
+		t.Fatalf("requests with images got prompts %q and %q, want none", a, b)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"string content", `{"messages": [{"role": "user", "content": "hi"}]}`, "hi"},
+		{"text parts", `{"messages": [{"role": "user", "content": [{"type": "text", "text": "a"}, {"type": "text", "text": "b"}]}]}`, "a\nb"},
+		{"anthropic image", `{"messages": [{"role": "user", "content": [{"type": "text", "text": "a"}, {"type": "image", "source": {"type": "base64", "media_type": "image/png", "data": "AAAA"}}]}]}`, ""},
+		{"gemini text", `{"contents": [{"role": "user", "parts": [{"text": "hi"}]}]}`, "hi"},
+		{"gemini image", `{"contents": [{"role": "user", "parts": [{"text": "hi"}, {"inline_data": {"mime_type": "image/png", "data": "AAAA"}}]}]}`, ""},
+	}
+	for _, tt := range tests {
+		if got := LastMessageText([]byte(tt.body)); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
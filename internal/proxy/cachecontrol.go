@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/cache"
+)
+
+// cacheDirectives is what a client asked of the semantic cache for one request.
+type cacheDirectives struct {
+	lookup  bool // may be answered from the cache
+	store   bool // the upstream response may be cached
+	options cache.LookupOptions
+}
+
+// parseCacheDirectives reads the client's cache headers:
+//
+//	Cache-Control: no-cache    skip the lookup but still store the fresh response
+//	Cache-Control: no-store    never store the response
+//	Cache-Control: max-age=N   only accept entries cached within N seconds
+//	X-Cache-Mode: exact|semantic|off
+//	X-Cache-Threshold: 0.92    minimum similarity for a semantic hit
+//	X-Cache-Max-Age: N         same as max-age, takes precedence
+func parseCacheDirectives(r *http.Request) (cacheDirectives, error) {
+	d := cacheDirectives{
+		lookup:  true,
+		store:   true,
+		options: cache.LookupOptions{Mode: cache.ModeSemantic},
+	}
+
+	maxAge := -1
+	for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(strings.ToLower(directive)), "=")
+		switch name {
+		case "no-cache":
+			d.lookup = false
+		case "no-store":
+			d.store = false
+		case "max-age":
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err != nil || seconds < 0 {
+				return d, fmt.Errorf("invalid Cache-Control max-age %q", value)
+			}
+			maxAge = seconds
+		}
+	}
+
+	if v := r.Header.Get("X-Cache-Max-Age"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			return d, fmt.Errorf("invalid X-Cache-Max-Age %q", v)
+		}
+		maxAge = seconds
+	}
+	switch {
+	case maxAge == 0:
+		d.lookup = false
+	case maxAge > 0:
+		d.options.MaxAge = time.Duration(maxAge) * time.Second
+	}
+
+	if v := r.Header.Get("X-Cache-Mode"); v != "" {
+		switch mode := cache.Mode(strings.ToLower(v)); mode {
+		case cache.ModeExact, cache.ModeSemantic:
+			d.options.Mode = mode
+		case cache.ModeOff:
+			d.options.Mode = mode
+			d.lookup = false
+			d.store = false
+		default:
+			return d, fmt.Errorf("invalid X-Cache-Mode %q, expected exact, semantic or off", v)
+		}
+	}
+
+	if v := r.Header.Get("X-Cache-Threshold"); v != "" {
+		threshold, err := strconv.ParseFloat(v, 64)
+		if err != nil || threshold <= 0 || threshold > 1 {
+			return d, fmt.Errorf("invalid X-Cache-Threshold %q, expected a number in (0, 1]", v)
+		}
+		d.options.Threshold = threshold
+	}
+
+	return d, nil
+}
+
+// gatewayCacheHeaders are only meant for the gateway and are not forwarded upstream.
+var gatewayCacheHeaders = []string{"X-Cache-Mode", "X-Cache-Threshold", "X-Cache-Max-Age"}
+
+func stripCacheHeaders(h http.Header) {
+	for _, name := range gatewayCacheHeaders {
+		h.Del(name)
+	}
+}
+
+// setCacheHitHeaders explains a cache hit to the client.
+func setCacheHitHeaders(h http.Header, hit *cache.Hit) {
+	h.Set("X-Cache-Status", "HIT")
+	h.Set("X-Cache-Key", hit.Entry.PromptHash)
+	h.Set("X-Cache-Entry-Id", strconv.FormatInt(hit.Entry.ID, 10))
+	h.Set("X-Cache-Similarity", strconv.FormatFloat(hit.Similarity, 'f', 4, 64))
+	h.Set("Age", strconv.Itoa(max(0, int(hit.Age().Seconds()))))
+	if hit.Namespace != "" {
+		h.Set("X-Cache-Namespace", hit.Namespace)
+	}
+}
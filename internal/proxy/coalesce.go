@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/cache"
+)
+
+// flightGroup deduplicates concurrent backend calls for the same cache key
+// within this process. The first request becomes the leader and fetches from
+// the backend; the others wait and replay its response.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+type flight struct {
+	done chan struct{}
+
+	// Set by the leader before done is closed, the cacheable (decoded,
+	// non-streamed) form of the response. nil if it couldn't be captured.
+	resp *cache.Response
+}
+
+// flightKey identifies requests whose responses are interchangeable: the same
+// tenant, path and model, and the same body once re-encoded, so whitespace and
+// key order don't matter but every other field does.
+func flightKey(tenantID int, path, model string, body []byte) string {
+	var v any
+	if err := json.Unmarshal(body, &v); err == nil {
+		if canonical, err := json.Marshal(v); err == nil {
+			body = canonical
+		}
+	}
+	sum := sha256.Sum256(body)
+	return fmt.Sprintf("%d:%s:%s:%s", tenantID, path, model, hex.EncodeToString(sum[:]))
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: make(map[string]*flight)}
+}
+
+// join returns the in-flight call for key, creating it if there is none.
+// leader is true when the caller created it and must call finish.
+func (g *flightGroup) join(key string) (f *flight, leader bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if f, ok := g.calls[key]; ok {
+		return f, false
+	}
+
+	f = &flight{done: make(chan struct{})}
+	g.calls[key] = f
+	return f, true
+}
+
+// finish publishes the leader's response and wakes every waiter.
+func (g *flightGroup) finish(key string, f *flight) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+
+	close(f.done)
+}
+
+// wait blocks until the leader finishes and reports whether its response can
+// be shared. Only successful responses are; otherwise waiters go to the
+// backend themselves.
+func (f *flight) wait(ctx context.Context) bool {
+	select {
+	case <-f.done:
+		return f.resp != nil && f.resp.StatusCode == http.StatusOK && len(f.resp.Body) > 0
+	case <-ctx.Done():
+		return false
+	}
+}
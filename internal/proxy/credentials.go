@@ -0,0 +1,31 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/vault"
+)
+
+// credentialsKey carries a tenant's upstream credentials to the reverse
+// proxy's director, which adds those of the backend it's sending to.
+type credentialsKey struct{}
+
+func withCredentials(ctx context.Context, creds []vault.Credential) context.Context {
+	if len(creds) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, credentialsKey{}, creds)
+}
+
+func credentialsFrom(ctx context.Context) []vault.Credential {
+	creds, _ := ctx.Value(credentialsKey{}).([]vault.Credential)
+	return creds
+}
+
+// authorize swaps the client's gateway credentials in an upstream request
+// for the tenant's credentials for backendURL.
+func authorize(req *http.Request, backendURL string, creds []vault.Credential) {
+	vault.StripClientAuth(req.Header)
+	vault.Inject(req, backendURL, creds)
+}
@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/cache"
+)
+
+// Upstream headers kept with a cached response and replayed on hits. Anything
+// describing the connection, the body's framing or the moment it was sent
+// (Date, rate limit counters) is left out.
+var replayedHeaders = []string{
+	"Content-Type",
+	"Content-Language",
+	"X-Request-Id",
+	"Request-Id",
+	"Openai-Model",
+	"Openai-Organization",
+	"Openai-Processing-Ms",
+	"Openai-Version",
+}
+
+// Headers with these prefixes are kept too, e.g. X-Usage-Total-Tokens
+var replayedHeaderPrefixes = []string{"X-Usage-", "X-Model-"}
+
+// Cached bodies smaller than this aren't worth compressing on replay
+const minGzipSize = 1024
+
+// captureResponse turns a recorded backend response into its cached form:
+// the body is decoded and, for event streams, assembled into the equivalent
+// non-streamed response. It returns false when the body can't be decoded.
+func captureResponse(statusCode int, header http.Header, body []byte) (*cache.Response, bool) {
+	encoding := strings.ToLower(strings.TrimSpace(header.Get("Content-Encoding")))
+	decoded, err := decodeBody(encoding, body)
+	if err != nil {
+		log.Printf("⚠️  Can't decode %s response body: %v", encoding, err)
+		return nil, false
+	}
+
+	resp := &cache.Response{
+		StatusCode:      statusCode,
+		Header:          make(map[string]string),
+		Body:            decoded,
+		ContentEncoding: encoding,
+	}
+	for name, values := range header {
+		if len(values) > 0 && isReplayedHeader(name) {
+			resp.Header[name] = values[0]
+		}
+	}
+
+	// Streamed responses are cached in their assembled, non-streamed form
+	if isEventStream(header) {
+		assembled, ok := assembleStream(decoded)
+		if !ok {
+			log.Printf("⚠️  Unrecognized event stream, response won't be cached")
+			return nil, false
+		}
+		resp.Body = assembled
+		resp.Header["Content-Type"] = "application/json"
+	}
+
+	return resp, true
+}
+
+func isReplayedHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	for _, h := range replayedHeaders {
+		if name == h {
+			return true
+		}
+	}
+	for _, prefix := range replayedHeaderPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeBody undoes a Content-Encoding. Only the encodings the standard
+// library can read are supported.
+func decodeBody(encoding string, body []byte) ([]byte, error) {
+	switch encoding {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	case "deflate":
+		// Meant to be zlib-wrapped, but some servers send raw deflate
+		if zr, err := zlib.NewReader(bytes.NewReader(body)); err == nil {
+			defer zr.Close()
+			return io.ReadAll(zr)
+		}
+		fr := flate.NewReader(bytes.NewReader(body))
+		defer fr.Close()
+		return io.ReadAll(fr)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// writeCachedResponse replays a cached response with its stored status and
+// headers. Bodies are gzipped again for clients that accept it.
+func writeCachedResponse(w http.ResponseWriter, r *http.Request, resp *cache.Response) {
+	for name, value := range resp.Header {
+		w.Header().Set(name, value)
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	statusCode := resp.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	if len(resp.Body) < minGzipSize || !acceptsGzip(r) {
+		w.WriteHeader(statusCode)
+		w.Write(resp.Body)
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(statusCode)
+
+	zw := gzip.NewWriter(w)
+	zw.Write(resp.Body)
+	zw.Close()
+}
+
+// acceptsGzip reports whether Accept-Encoding allows gzip, e.g. "gzip, br" but not "gzip;q=0"
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.TrimSpace(coding)
+		if !strings.EqualFold(coding, "gzip") && coding != "*" {
+			continue
+		}
+
+		q := 1.0
+		if name, value, ok := strings.Cut(params, "="); ok && strings.TrimSpace(name) == "q" {
+			q, _ = strconv.ParseFloat(strings.TrimSpace(value), 64)
+		}
+		return q > 0
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+)
+
+// Provider name reported for the tenant's own backends
+const primaryProvider = "primary"
+
+// Fallback steps aren't started with less of the request's deadline left
+const minFallbackTime = time.Second
+
+// fallbackWorthy reports whether a response should be handed to the next
+// step of the fallback chain.
+func fallbackWorthy(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// runFallbacks tries the fallback chain in order after the tenant's own
+// backends answered with failed. Every step but the last is held back if it
+// fails too. It returns the last step that answered, or nil if none could be
+// tried, in which case the caller's own answer stands. Steps run within ctx's
+// deadline, each also limited to its own timeout.
+func (h *Handler) runFallbacks(ctx context.Context, w http.ResponseWriter, r *http.Request, steps []models.TenantFallback, body []byte, stream bool, start time.Time, failed int) (*responseRecorder, *models.TenantFallback) {
+	var last *responseRecorder
+	var lastStep *models.TenantFallback
+
+	for i := range steps {
+		step := &steps[i]
+		if ctx.Err() != nil {
+			break
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < minFallbackTime {
+			log.Printf("⏭️  Request deadline too close for fallback %s, stopping", step.Provider)
+			break
+		}
+
+		upstream, err := h.backends.get(step.URL)
+		if err != nil {
+			log.Printf("❌ Invalid fallback URL: %s, error: %v", step.URL, err)
+			continue
+		}
+		if !h.health.Healthy(step.URL) {
+			log.Printf("⏭️  Fallback %s is out of rotation, skipping", step.Provider)
+			continue
+		}
+
+		req := r.Clone(ctx)
+		stepBody := withModel(body, step.Model)
+		req.Body = io.NopCloser(bytes.NewReader(stepBody))
+		req.ContentLength = int64(len(stepBody))
+		if req, _, err = translateRequest(req, step.APIFormat, stepBody); err != nil {
+			log.Printf("⏭️  Can't translate request for fallback %s, skipping: %v", step.Provider, err)
+			continue
+		}
+		report, _, allowed := h.breakers.Get(step.URL).Allow()
+		if !allowed {
+			log.Printf("⏭️  Circuit open for fallback %s, skipping", step.Provider)
+			continue
+		}
+		if last != nil {
+			failed = last.statusCode
+		}
+		log.Printf("↪️  Got %d, falling back to %s (step %d)", failed, step.Provider, step.Position)
+
+		// req inherits the request deadline, the step's timeout may cut it shorter
+		stepCtx, cancel := context.WithTimeout(req.Context(), time.Duration(step.TimeoutMs)*time.Millisecond)
+		req = req.WithContext(stepCtx)
+
+		recorder := newResponseRecorder(w, stream, start)
+		recorder.mayFallback = i < len(steps)-1
+		recorder.Header().Set("X-Gateway-Backend", "fallback-"+strconv.Itoa(step.Position))
+		recorder.Header().Set("X-Gateway-Provider", step.Provider)
+		stepStart := time.Now()
+		upstream.proxy.ServeHTTP(recorder, req)
+		cancel()
+		report(outcome(recorder), time.Since(stepStart))
+		h.reportHealth(step.URL, recorder)
+
+		last, lastStep = recorder, step
+		if !recorder.held {
+			break
+		}
+	}
+
+	return last, lastStep
+}
+
+// withModel returns a JSON request body with its model replaced, or the body
+// as is if model is empty or the body isn't a JSON object.
+func withModel(body []byte, model string) []byte {
+	if model == "" {
+		return body
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return body
+	}
+	fields["model"], _ = json.Marshal(model)
+
+	rewritten, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return rewritten
+}
@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/auth"
+	"github.com/jackc/pgx/v5"
+)
+
+// Feedback lets a tenant report a cached answer as wrong. The entry is the
+// X-Cache-Entry-Id of the response; the original prompt and model are
+// optional and narrow the report down to the hit for that prompt.
+func (h *Handler) Feedback(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.GetTenantFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tenant, err := h.db.GetTenantByAPIKey(r.Context(), claims.APIKey)
+	if err != nil {
+		http.Error(w, "Tenant not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		EntryID int64  `json:"entry_id"`
+		Prompt  string `json:"prompt"`
+		Model   string `json:"model"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.EntryID <= 0 {
+		http.Error(w, "entry_id is required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.semanticCache.ReportBadHit(r.Context(), tenant.ID, req.EntryID, req.Model, req.Prompt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "No unreported hit for this cache entry", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("❌ Failed to record cache feedback: %v", err)
+		http.Error(w, "Failed to record feedback", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("👎 Bad cache hit reported by tenant %d for entry %d", tenant.ID, req.EntryID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
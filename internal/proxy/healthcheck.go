@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/balancer"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/breaker"
+)
+
+// HealthCheckOptions controls active probing of pool backends that have a
+// health_check_path.
+type HealthCheckOptions struct {
+	Interval       time.Duration // zero disables probing
+	Timeout        time.Duration
+	ExpectedStatus int
+}
+
+// StartHealthChecks probes backends every interval and reports the results
+// to the handler's health tracker. It stops when ctx is cancelled.
+func (h *Handler) StartHealthChecks(ctx context.Context, opts HealthCheckOptions) {
+	if opts.Interval <= 0 {
+		return
+	}
+
+	client := &http.Client{
+		Timeout: opts.Timeout,
+		// A redirect is an answer too, compare its status as is
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	go func() {
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.probeBackends(ctx, client, opts.ExpectedStatus)
+			}
+		}
+	}()
+}
+
+func (h *Handler) probeBackends(ctx context.Context, client *http.Client, expectedStatus int) {
+	targets, err := h.db.ListHealthCheckTargets(ctx)
+	if err != nil {
+		log.Printf("❌ Health checks: failed to list backends: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			url := strings.TrimSuffix(t.URL, "/") + "/" + strings.TrimPrefix(t.HealthCheckPath, "/")
+			h.health.ReportProbe(t.URL, probe(ctx, client, url, expectedStatus))
+		}()
+	}
+	wg.Wait()
+}
+
+func probe(ctx context.Context, client *http.Client, url string, expectedStatus int) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode != expectedStatus {
+		return fmt.Errorf("status %d, expected %d", resp.StatusCode, expectedStatus)
+	}
+	return nil
+}
+
+// unavailable is the balancer's skip hook: backends out of rotation or
+// with an open circuit.
+func (h *Handler) unavailable(t balancer.Target) bool {
+	return !h.health.Healthy(t.URL) || !h.breakers.Get(t.URL).Ready()
+}
+
+// pick chooses an available backend of the pool, or any backend if none is
+// available, as trying one beats failing the request outright.
+func (h *Handler) pick(pool *balancer.Pool, r *http.Request) (balancer.Target, func(time.Duration)) {
+	target, done, ok := pool.Pick(r, h.unavailable)
+	if !ok {
+		log.Printf("⚠️  No backend available, picking one anyway")
+		target, done, _ = pool.Pick(r, nil)
+	}
+	return target, done
+}
+
+// outcome classifies a proxied request for health tracking: 5xx responses
+// and timeouts are failures, and requests the client cancelled say nothing
+// about the backend.
+func outcome(recorder *responseRecorder) breaker.Outcome {
+	return callOutcome(recorder.statusCode, recorder.proxyErr)
+}
+
+// callOutcome classifies a backend call by its status and error, the same
+// way outcome does.
+func callOutcome(status int, err error) breaker.Outcome {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return breaker.Canceled
+	case err != nil || status >= 500:
+		return breaker.Failure
+	}
+	return breaker.Success
+}
+
+// reportHealth feeds the outcome of a proxied request into passive outlier
+// detection.
+func (h *Handler) reportHealth(url string, recorder *responseRecorder) {
+	h.reportOutcome(url, outcome(recorder))
+}
+
+func (h *Handler) reportOutcome(url string, o breaker.Outcome) {
+	if o != breaker.Canceled {
+		h.health.Report(url, o == breaker.Success)
+	}
+}
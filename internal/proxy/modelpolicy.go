@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+)
+
+// modelPolicy returns the tenant's model policy, or nil if it has none.
+func (h *Handler) modelPolicy(ctx context.Context, tenant *models.Tenant) (*models.TenantModelPolicy, error) {
+	routes, err := h.routes.For(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	return routes.Policy, nil
+}
+
+// matchModel reports whether model matches a policy pattern: a model name,
+// or a prefix ending in *.
+func matchModel(pattern, model string) bool {
+	pattern, model = strings.ToLower(pattern), strings.ToLower(model)
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(model, prefix)
+	}
+	return pattern == model
+}
+
+func matchAnyModel(patterns []string, model string) bool {
+	for _, pattern := range patterns {
+		if matchModel(pattern, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// modelRoute returns the position and rule of the first route for model,
+// or 0 and nil if the tenant's own pool serves it.
+func modelRoute(policy *models.TenantModelPolicy, model string) (int, *models.ModelRoute) {
+	if policy == nil || model == "" {
+		return 0, nil
+	}
+	for i := range policy.Routes {
+		if matchModel(policy.Routes[i].Model, model) {
+			return i + 1, &policy.Routes[i]
+		}
+	}
+	return 0, nil
+}
+
+// requestModel reads the model a JSON request body asks for, "" if none.
+func requestModel(body []byte) string {
+	var req struct {
+		Model string `json:"model"`
+	}
+	json.Unmarshal(body, &req)
+	return req.Model
+}
+
+// resolveAlias returns the model an alias stands for, or model itself if it
+// isn't one. An exact match wins; otherwise aliases are compared
+// case-insensitively in sorted order, so older policies with aliases that
+// differ only in case still resolve the same way every time.
+func resolveAlias(aliases map[string]string, model string) string {
+	if target, ok := aliases[model]; ok {
+		return target
+	}
+	names := make([]string, 0, len(aliases))
+	for alias := range aliases {
+		names = append(names, alias)
+	}
+	slices.Sort(names)
+	for _, alias := range names {
+		if strings.EqualFold(alias, model) {
+			return aliases[alias]
+		}
+	}
+	return model
+}
+
+// hasModelField reports whether body is a JSON object with a model field.
+func hasModelField(body []byte) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return false
+	}
+	_, ok := fields["model"]
+	return ok
+}
+
+// modelDecision is what a tenant's model policy says about a request.
+type modelDecision struct {
+	model  string // the model to send upstream, with aliases resolved
+	status int    // set when the request is refused
+	reason string
+}
+
+// applyModelPolicy resolves the alias of the model a request asks for and
+// checks the result against the policy's allow and deny lists.
+func applyModelPolicy(policy *models.TenantModelPolicy, body []byte) modelDecision {
+	var req struct {
+		Model *string `json:"model"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return modelDecision{status: http.StatusBadRequest, reason: "Request body must be a JSON object with a string model"}
+	}
+	if req.Model == nil || *req.Model == "" {
+		if len(policy.AllowedModels) > 0 {
+			return modelDecision{status: http.StatusBadRequest, reason: "Request must name a model"}
+		}
+		return modelDecision{}
+	}
+
+	model := resolveAlias(policy.Aliases, *req.Model)
+
+	if matchAnyModel(policy.DeniedModels, model) ||
+		(len(policy.AllowedModels) > 0 && !matchAnyModel(policy.AllowedModels, model)) {
+		return modelDecision{model: model, status: http.StatusForbidden, reason: fmt.Sprintf("Model %q is not allowed for this tenant", model)}
+	}
+	return modelDecision{model: model}
+}
@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/balancer"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+)
+
+// route is where a tenant's requests can go: a load balanced pool, then,
+// for LLM requests it fails, the fallback chain.
+type route struct {
+	pool       *balancer.Pool
+	format     string // API format the pool's backends speak
+	fallbacks  []models.TenantFallback
+	modelRoute int // position of the model route the pool serves, zero for the tenant's own pool
+}
+
+// routeFor returns the balancer for a tenant's enabled pool backends, or for
+// its backend_url alone when the pool is empty, and its enabled fallbacks.
+// Requests for a model the tenant's policy routes elsewhere go to that
+// route's backend instead.
+func (h *Handler) routeFor(ctx context.Context, tenant *models.Tenant, policy *models.TenantModelPolicy, model string) (*route, error) {
+	routes, err := h.routes.For(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	h.backends.use(tenant.ID, routes.URLs)
+
+	rt := &route{format: tenant.APIFormat, fallbacks: routes.Fallbacks}
+	if position, mr := modelRoute(policy, model); mr != nil {
+		key := fmt.Sprintf("%d:route:%s", tenant.ID, mr.URL)
+		rt.pool = h.balancers.Pool(key, balancer.RoundRobin, "", []balancer.Target{{URL: mr.URL, Weight: 1}})
+		rt.modelRoute = position
+		if mr.APIFormat != "" {
+			rt.format = mr.APIFormat
+		}
+	} else {
+		rt.pool = h.balancers.Pool(strconv.Itoa(tenant.ID), balancer.Strategy(tenant.LBStrategy), tenant.LBHashHeader, routes.Targets)
+	}
+	return rt, nil
+}
+
+// label names the backend a request went to in the X-Gateway-Backend header.
+func (rt *route) label(t balancer.Target) string {
+	if rt.modelRoute > 0 {
+		return "route-" + strconv.Itoa(rt.modelRoute)
+	}
+	return backendLabel(t)
+}
+
+// backendLabel names a backend in the X-Gateway-Backend header without
+// revealing its URL.
+func backendLabel(t balancer.Target) string {
+	if t.ID == 0 {
+		return "default"
+	}
+	return strconv.Itoa(t.ID)
+}
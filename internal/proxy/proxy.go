@@ -6,30 +6,103 @@ import (
 	"encoding/json"
 	"io"
 	"log"
+	"math"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/HanTheDev/multi-tenant-api-gateway/internal/auth"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/balancer"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/breaker"
 	"github.com/HanTheDev/multi-tenant-api-gateway/internal/cache"
 	"github.com/HanTheDev/multi-tenant-api-gateway/internal/db"
 	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/providers"
 	"github.com/HanTheDev/multi-tenant-api-gateway/internal/ratelimit"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/routing"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/vault"
 )
 
 type Handler struct {
 	db            *db.DB
 	rateLimiter   *ratelimit.RateLimiter
 	semanticCache *cache.SemanticCache
+	opts          Options
+	flights       *flightGroup
+	backends      *backendRegistry
+	balancers     *balancer.Registry
+	health        *balancer.Health
+	breakers      *breaker.Set
+	retries       *retryBudget
+	vault         *vault.Vault
+	routes        *routing.Store
+
+	// Stale entries being refreshed in the background
+	refreshing sync.Map
 }
 
-func NewHandler(database *db.DB, limiter *ratelimit.RateLimiter, semCache *cache.SemanticCache) *Handler {
+// Options tunes the proxy handler.
+type Options struct {
+	// Coalesce identical concurrent LLM prompts into one backend call
+	Coalesce bool
+	// Also coordinate coalescing across gateway instances with a Redis lock
+	CoalesceRedisLock bool
+	// How long an instance may hold the Redis lock while fetching
+	CoalesceLockTTL time.Duration
+
+	// Cached answers replayed to streaming clients are split into events of
+	// StreamChunkSize characters, StreamPacing apart
+	StreamChunkSize int
+	StreamPacing    time.Duration
+
+	// Connections to backends, shared by every request to the same backend URL
+	Transport TransportOptions
+
+	// Retrying failed backend calls
+	Retry RetryOptions
+
+	// Backend health and circuit breakers, shared with the admin API that
+	// reports them
+	Health   *balancer.Health
+	Breakers *breaker.Set
+
+	// Upstream credentials, shared with the admin API that manages them
+	Vault *vault.Vault
+
+	// Tenants' backends, fallbacks and model policies, shared with the
+	// admin API that changes them
+	Routes *routing.Store
+}
+
+func NewHandler(database *db.DB, limiter *ratelimit.RateLimiter, semCache *cache.SemanticCache, opts Options) *Handler {
+	health := opts.Health
+	if health == nil {
+		health = balancer.NewHealth(balancer.HealthOptions{})
+	}
+	breakers := opts.Breakers
+	if breakers == nil {
+		breakers = breaker.NewSet(breaker.Options{}, nil)
+	}
+	routes := opts.Routes
+	if routes == nil {
+		routes = routing.New(database, 0)
+	}
+
 	return &Handler{
 		db:            database,
 		rateLimiter:   limiter,
 		semanticCache: semCache,
+		opts:          opts,
+		flights:       newFlightGroup(),
+		backends:      newBackendRegistry(opts.Transport),
+		balancers:     balancer.NewRegistry(),
+		health:        health,
+		breakers:      breakers,
+		retries:       newRetryBudget(opts.Retry.BudgetRatio, opts.Retry.BudgetBurst),
+		vault:         opts.Vault,
+		routes:        routes,
 	}
 }
 
@@ -80,57 +153,158 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 	}
 
+	// Enforce the tenant's model policy, resolving aliases before the body
+	// is looked up in the cache or forwarded
+	policy, err := h.modelPolicy(r.Context(), tenant)
+	if err != nil {
+		log.Printf("❌ Failed to load model policy for tenant %d: %v", tenant.ID, err)
+		http.Error(w, "Failed to load model policy", http.StatusInternalServerError)
+		return
+	}
+	// LLM requests must pass it; any other request naming a model must too
+	model := requestModel(bodyBytes)
+	if policy != nil && len(bodyBytes) > 0 && (h.isLLMRequest(r) || hasModelField(bodyBytes)) {
+		decision := applyModelPolicy(policy, bodyBytes)
+		if decision.status != 0 {
+			log.Printf("🚫 %s (tenant %d)", decision.reason, tenant.ID)
+			http.Error(w, decision.reason, decision.status)
+			return
+		}
+		if decision.model != model {
+			log.Printf("🏷️  Model %q resolved to %q for tenant %d", model, decision.model, tenant.ID)
+			bodyBytes = withModel(bodyBytes, decision.model)
+			r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+			r.ContentLength = int64(len(bodyBytes))
+			model = decision.model
+		}
+	}
+
 	// Try semantic cache for LLM requests
+	var prompt, promptHash string
+	directives := cacheDirectives{}
+	stream := false
 	if h.isLLMRequest(r) && len(bodyBytes) > 0 {
-		prompt := h.extractPromptFromBody(bodyBytes)
-		if prompt != "" {
-			log.Printf("🔍 Checking cache for prompt: %s", prompt[:min(50, len(prompt))])
+		prompt = h.extractPromptFromBody(bodyBytes)
+		stream = isStreamRequest(bodyBytes)
+	}
+	if prompt != "" {
+		directives, err = parseCacheDirectives(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		stripCacheHeaders(r.Header)
+		promptHash = h.semanticCache.HashPrompt(r.Context(), tenant.ID, model, prompt)
+		w.Header().Set("X-Cache-Key", promptHash)
 
-			cachedResponse, hit, err := h.semanticCache.GetCachedResponse(r.Context(), tenant.ID, prompt)
-			if err == nil && hit {
-				log.Printf("✅ 🎯 CACHE HIT for tenant %d", tenant.ID)
+		if directives.lookup {
+			log.Printf("🔍 Checking cache for prompt: %s", prompt[:min(50, len(prompt))])
 
-				// Write cached response
-				w.Header().Set("Content-Type", "application/json")
-				w.Header().Set("X-Cache-Status", "HIT")
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte(cachedResponse))
+			hit, ok, err := h.semanticCache.GetCachedResponse(r.Context(), tenant.ID, model, prompt, directives.options)
+			if err == nil && ok {
+				cacheStatus := "HIT"
+				if hit.Stale {
+					// Serve it anyway and refresh it in the background
+					cacheStatus = "STALE"
+					if directives.store {
+						h.revalidate(tenant, policy, r, bodyBytes, hit)
+					}
+				}
+				log.Printf("✅ 🎯 CACHE %s for tenant %d (similarity %.4f)", cacheStatus, tenant.ID, hit.Similarity)
+				h.writeCacheHit(w, r, hit, cacheStatus, stream)
 
 				// Log access with cache hit
 				elapsed := time.Since(startTime)
-				h.logAccess(r.Context(), tenant.ID, r.URL.Path, r.Method, http.StatusOK, elapsed, r.ContentLength, int64(len(cachedResponse)))
+				h.logAccess(r.Context(), tenant.ID, r.URL.Path, r.Method, http.StatusOK, elapsed, answeredBy{}, r.ContentLength, int64(len(hit.Entry.Response)))
 				log.Printf("✅ Request completed (CACHED) in %dms", elapsed.Milliseconds())
 				return
 			}
 			log.Printf("❌ Cache MISS for tenant %d", tenant.ID)
+			w.Header().Set("X-Cache-Status", "MISS")
+		} else {
+			log.Printf("⏭️  Cache lookup bypassed by client for tenant %d", tenant.ID)
+			w.Header().Set("X-Cache-Status", "BYPASS")
+		}
+	}
+
+	// Coalesce identical in-flight requests so only one of them reaches the
+	// backend, unless the client won't take an answer it didn't ask for
+	var leader *flight
+	releaseFill := func() {}
+	fillHandedOff := false
+	if prompt != "" && h.opts.Coalesce && directives.lookup {
+		key := flightKey(tenant.ID, r.URL.Path, model, bodyBytes)
+		f, isLeader := h.flights.join(key)
+		if !isLeader {
+			log.Printf("⏳ Waiting on in-flight request for tenant %d", tenant.ID)
+			if f.wait(r.Context()) {
+				w.Header().Set("X-Cache-Status", "COALESCED")
+				h.writeCachedBody(w, r, f.resp, stream)
+				elapsed := time.Since(startTime)
+				h.logAccess(r.Context(), tenant.ID, r.URL.Path, r.Method, f.resp.StatusCode, elapsed, answeredBy{}, r.ContentLength, int64(len(f.resp.Body)))
+				log.Printf("✅ Request completed (COALESCED) in %dms", elapsed.Milliseconds())
+				return
+			}
+		} else {
+			leader = f
+			defer h.flights.finish(key, f)
+
+			// Another gateway instance may already be fetching this prompt
+			if h.opts.CoalesceRedisLock {
+				release, acquired := h.semanticCache.AcquireFillLock(r.Context(), tenant.ID, promptHash, h.opts.CoalesceLockTTL)
+				if acquired {
+					releaseFill = release
+					defer func() {
+						// Unless the cache store below took it over
+						if !fillHandedOff {
+							release()
+						}
+					}()
+				} else {
+					waitCtx, cancel := context.WithTimeout(r.Context(), h.opts.CoalesceLockTTL)
+					hit, ok := h.semanticCache.WaitForFill(waitCtx, tenant.ID, model, prompt)
+					cancel()
+					if ok {
+						h.writeCacheHit(w, r, hit, "COALESCED", stream)
+						leader.resp = hit.Response()
+
+						elapsed := time.Since(startTime)
+						h.logAccess(r.Context(), tenant.ID, r.URL.Path, r.Method, http.StatusOK, elapsed, answeredBy{}, r.ContentLength, int64(len(hit.Entry.Response)))
+						log.Printf("✅ Request completed (COALESCED) in %dms", elapsed.Milliseconds())
+						return
+					}
+				}
+			}
 		}
 	}
 
-	// Parse backend URL
-	backendURL, err := url.Parse(tenant.BackendURL)
+	// The tenant's backends, load balanced per attempt
+	rt, err := h.routeFor(r.Context(), tenant, policy, model)
 	if err != nil {
-		log.Printf("❌ Invalid backend URL: %s, error: %v", tenant.BackendURL, err)
-		http.Error(w, "Invalid backend URL", http.StatusInternalServerError)
+		log.Printf("❌ Failed to load backends for tenant %d: %v", tenant.ID, err)
+		http.Error(w, "Failed to load backends", http.StatusInternalServerError)
 		return
 	}
-
-	// Create reverse proxy with better transport settings
-	proxy := httputil.NewSingleHostReverseProxy(backendURL)
-
-	// Configure transport with retries and timeouts
-	proxy.Transport = &http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 10,
-		IdleConnTimeout:     90 * time.Second,
-		DisableKeepAlives:   false,
+	var fallbacks []models.TenantFallback
+	if h.isLLMRequest(r) {
+		fallbacks = rt.fallbacks
+	}
+	creds, err := h.vault.Credentials(r.Context(), tenant.ID)
+	if err != nil {
+		log.Printf("❌ Failed to load upstream credentials for tenant %d: %v", tenant.ID, err)
+		http.Error(w, "Failed to load upstream credentials", http.StatusInternalServerError)
+		return
 	}
 
-	// Add timeout context (60 seconds for LLM, 30 for others)
+	// Add timeout context (60 seconds for LLM, 30 for others), which also
+	// bounds the fallback chain. Every backend gets the tenant's credentials
+	// for it instead of the client's.
+	clientCtx := withCredentials(r.Context(), creds)
 	timeout := 30 * time.Second
 	if h.isLLMRequest(r) {
 		timeout = 60 * time.Second
 	}
-	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	ctx, cancel := context.WithTimeout(clientCtx, timeout)
 	defer cancel()
 	r = r.WithContext(ctx)
 
@@ -139,104 +313,179 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 	}
 
-	// Better error handler
-	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-		log.Printf("❌ Proxy error: %v", err)
-
-		if err == context.DeadlineExceeded {
-			http.Error(w, "Backend timeout - request took too long", http.StatusGatewayTimeout)
-		} else if strings.Contains(err.Error(), "no such host") {
-			http.Error(w, "Backend DNS resolution failed", http.StatusBadGateway)
-		} else if strings.Contains(err.Error(), "connection refused") {
-			http.Error(w, "Backend connection refused", http.StatusBadGateway)
-		} else {
-			http.Error(w, "Bad Gateway: "+err.Error(), http.StatusBadGateway)
-		}
-	}
-
 	// Modify request path
 	originalPath := r.URL.Path
 	if strings.HasPrefix(originalPath, "/api") {
 		r.URL.Path = strings.TrimPrefix(originalPath, "/api")
-		log.Printf("🔀 Proxying: %s%s", backendURL.String(), r.URL.Path)
 	}
 
-	// Capture response for logging and caching
-	recorder := &responseRecorder{
-		ResponseWriter: w,
-		statusCode:     http.StatusOK,
-		body:           &bytes.Buffer{},
-		headerWritten:  false,
+	// Clients speak OpenAI's format, the tenant's backends may not
+	upstreamReq, upstreamBody, err := translateRequest(r, rt.format, bodyBytes)
+	if err != nil {
+		log.Printf("❌ Can't translate request for %s backend: %v", rt.format, err)
+		http.Error(w, "Invalid request: "+err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// Proxy the request with retry logic
-	maxRetries := 2
-	var lastErr error
-
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		if attempt > 0 {
-			log.Printf("🔄 Retry attempt %d/%d", attempt, maxRetries)
-			time.Sleep(time.Duration(attempt) * 500 * time.Millisecond) // Exponential backoff
+	// Proxy the request, retrying while it's safe to. Each attempt's
+	// response is held back if it may be retried or handed to a fallback,
+	// and only the final one reaches the client; streamed responses are
+	// flushed chunk by chunk while being recorded for logging and caching.
+	h.retries.deposit(tenant.ID)
+	proxyStart := time.Now()
+	var recorder *responseRecorder
+	var served balancer.Target
+	answer := answeredBy{provider: primaryProvider}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && len(upstreamBody) > 0 {
+			upstreamReq.Body = io.NopCloser(bytes.NewBuffer(upstreamBody))
+		}
 
-			// Reset body and recorder for retry
-			if len(bodyBytes) > 0 {
-				r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+		// Retries may land on another backend of the pool
+		target, done := h.pick(rt.pool, r)
+		upstream, err := h.backends.get(target.URL)
+		if err != nil {
+			log.Printf("❌ Invalid backend URL: %s, error: %v", target.URL, err)
+			done(0)
+			if recorder != nil {
+				break
 			}
+			http.Error(w, "Invalid backend URL", http.StatusInternalServerError)
+			return
+		}
 
-			// Create new recorder for retry
-			recorder = &responseRecorder{
-				ResponseWriter: w,
-				statusCode:     http.StatusOK,
-				body:           &bytes.Buffer{},
-				headerWritten:  false,
+		// Fail fast while the backend's circuit is open
+		report, retryAfter, allowed := h.breakers.Get(target.URL).Allow()
+		if !allowed {
+			done(0)
+			log.Printf("⚡ Circuit open for %s, failing fast", target.URL)
+			if recorder != nil {
+				break
 			}
+			recorder = newResponseRecorder(w, stream, proxyStart)
+			recorder.mayFallback = len(fallbacks) > 0
+			recorder.Header().Set("X-Gateway-Backend", rt.label(target))
+			recorder.Header().Set("X-Gateway-Provider", primaryProvider)
+			recorder.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			http.Error(recorder, "Backend unavailable, circuit open", http.StatusServiceUnavailable)
+			break
 		}
 
-		// Try the proxy
-		proxy.ServeHTTP(recorder, r)
-
-		// If successful or client error (4xx), don't retry
-		if recorder.statusCode >= 200 && recorder.statusCode < 500 {
+		served = target
+		log.Printf("🔀 Proxying: %s%s", upstream.target.String(), upstreamReq.URL.Path)
+
+		recorder = newResponseRecorder(w, stream, proxyStart)
+		recorder.mayRetry = attempt < h.opts.Retry.MaxRetries
+		recorder.mayFallback = len(fallbacks) > 0
+		recorder.Header().Set("X-Gateway-Backend", rt.label(target))
+		recorder.Header().Set("X-Gateway-Provider", primaryProvider)
+		attemptStart := time.Now()
+		upstream.proxy.ServeHTTP(recorder, upstreamReq)
+		elapsed := time.Since(attemptStart)
+		done(elapsed)
+		report(outcome(recorder), elapsed)
+		h.reportHealth(target.URL, recorder)
+		if !recorder.held {
 			break
 		}
+		if attempt >= h.opts.Retry.MaxRetries || !isRetryable(recorder.statusCode, recorder.header, recorder.proxyErr) {
+			break // held for the fallback chain
+		}
 
-		lastErr = err
-
-		// Only retry on 5xx errors
-		if attempt < maxRetries {
-			log.Printf("⚠️  Got %d, will retry...", recorder.statusCode)
+		delay, ok := h.opts.Retry.retryDelay(attempt, recorder.header)
+		if !ok {
+			log.Printf("⏭️  Backend asked to retry after more than %s, not retrying", h.opts.Retry.MaxDelay)
+			break
+		}
+		if !h.retries.withdraw(tenant.ID) {
+			log.Printf("🚫 Retry budget exhausted for tenant %d", tenant.ID)
+			break
+		}
+		log.Printf("🔄 Got %d (%v), retry %d/%d in %dms", recorder.statusCode, recorder.proxyErr, attempt+1, h.opts.Retry.MaxRetries, delay.Milliseconds())
+		if !sleepCtx(ctx, delay) {
+			break
+		}
+	}
+	answer.backend = served.URL
+
+	// Fail over along the fallback chain, then send whatever answer is
+	// still held back
+	if recorder.held && len(fallbacks) > 0 {
+		if rec, step := h.runFallbacks(ctx, w, r, fallbacks, bodyBytes, stream, proxyStart, recorder.statusCode); rec != nil {
+			recorder = rec
+			answer = answeredBy{backend: step.URL, provider: step.Provider, fallbackStep: step.Position}
 		}
 	}
+	recorder.release()
+	answer.ttfb = recorder.firstByte
 
 	if recorder.statusCode >= 500 {
-		log.Printf("❌ All retries failed, last error: %v", lastErr)
+		log.Printf("❌ Backend failed with %d, last error: %v", recorder.statusCode, recorder.proxyErr)
 	} else {
 		log.Printf("✅ Response: %d", recorder.statusCode)
 	}
+	if recorder.firstByte > 0 {
+		log.Printf("⚡ First byte after %dms", recorder.firstByte.Milliseconds())
+	}
+
+	var cacheable *cache.Response
+	if prompt != "" && recorder.statusCode == http.StatusOK {
+		cacheable, _ = captureResponse(recorder.statusCode, w.Header(), recorder.body.Bytes())
+	}
+
+	// Share the response with coalesced waiters
+	if leader != nil {
+		leader.resp = cacheable
+	}
 
 	// Cache successful LLM responses
-	if h.isLLMRequest(r) && recorder.statusCode == http.StatusOK && len(bodyBytes) > 0 {
-		prompt := h.extractPromptFromBody(bodyBytes)
-		if prompt != "" && recorder.body.Len() > 0 {
-			go func() {
-				ctx := context.Background()
-				err := h.semanticCache.StoreCachedResponse(ctx, tenant.ID, prompt, recorder.body.String())
-				if err != nil {
-					log.Printf("❌ Failed to cache response: %v", err)
-				} else {
-					log.Printf("✅ Response cached for tenant %d", tenant.ID)
-				}
-			}()
-		}
+	if cacheable != nil && directives.store && cacheable.StatusCode == http.StatusOK && len(cacheable.Body) > 0 {
+		fillHandedOff = true
+		go func() {
+			// Other instances wait on the fill lock until the entry is stored
+			defer releaseFill()
+
+			ctx := context.Background()
+			err := h.semanticCache.StoreCachedResponse(ctx, tenant.ID, model, prompt, cacheable)
+			if err != nil {
+				log.Printf("❌ Failed to cache response: %v", err)
+			} else {
+				log.Printf("✅ Response cached for tenant %d", tenant.ID)
+			}
+		}()
 	}
 
 	// Log access
 	elapsed := time.Since(startTime)
-	h.logAccess(r.Context(), tenant.ID, originalPath, r.Method, recorder.statusCode, elapsed, r.ContentLength, int64(recorder.size))
+	h.logAccess(r.Context(), tenant.ID, originalPath, r.Method, recorder.statusCode, elapsed, answer, r.ContentLength, int64(recorder.size))
 
 	log.Printf("✅ Request completed in %dms", elapsed.Milliseconds())
 }
 
+// writeCacheHit answers a request from the cache
+func (h *Handler) writeCacheHit(w http.ResponseWriter, r *http.Request, hit *cache.Hit, cacheStatus string, stream bool) {
+	setCacheHitHeaders(w.Header(), hit)
+	w.Header().Set("X-Cache-Status", cacheStatus)
+	h.writeCachedBody(w, r, hit.Response(), stream)
+}
+
+// writeCachedBody writes a cached response, replayed as an event stream if
+// the client asked for one.
+func (h *Handler) writeCachedBody(w http.ResponseWriter, r *http.Request, resp *cache.Response, stream bool) {
+	if stream {
+		for name, value := range resp.Header {
+			w.Header().Set(name, value)
+		}
+		if err := replayStream(r.Context(), w, resp.Body, h.opts.StreamChunkSize, h.opts.StreamPacing); err != nil {
+			log.Printf("⚠️  Stream replay interrupted: %v", err)
+		}
+		return
+	}
+
+	writeCachedResponse(w, r, resp)
+}
+
 func (h *Handler) isLLMRequest(r *http.Request) bool {
 	llmPaths := []string{"/v1/chat/completions", "/v1/completions", "/api/chat", "/llm", "/generate"}
 	for _, path := range llmPaths {
@@ -248,20 +497,16 @@ func (h *Handler) isLLMRequest(r *http.Request) bool {
 }
 
 func (h *Handler) extractPromptFromBody(bodyBytes []byte) string {
+	// OpenAI, Anthropic and Gemini chat formats
+	if prompt := providers.LastMessageText(bodyBytes); prompt != "" {
+		return prompt
+	}
+
 	var reqBody map[string]interface{}
 	if err := json.Unmarshal(bodyBytes, &reqBody); err != nil {
 		return ""
 	}
 
-	// OpenAI format
-	if messages, ok := reqBody["messages"].([]interface{}); ok && len(messages) > 0 {
-		if lastMsg, ok := messages[len(messages)-1].(map[string]interface{}); ok {
-			if content, ok := lastMsg["content"].(string); ok {
-				return content
-			}
-		}
-	}
-
 	// Simple prompt format
 	if prompt, ok := reqBody["prompt"].(string); ok {
 		return prompt
@@ -275,7 +520,17 @@ func (h *Handler) extractPromptFromBody(bodyBytes []byte) string {
 	return ""
 }
 
-func (h *Handler) logAccess(ctx context.Context, tenantID int, endpoint, method string, statusCode int, elapsed time.Duration, reqSize, respSize int64) {
+// answeredBy describes who answered a request upstream, empty when the
+// request never reached a backend.
+type answeredBy struct {
+	backend      string
+	provider     string
+	fallbackStep int           // position in the fallback chain, zero for the tenant's own backends
+	ttfb         time.Duration // how long the backend took to send the first body byte
+}
+
+// logAccess records a request.
+func (h *Handler) logAccess(ctx context.Context, tenantID int, endpoint, method string, statusCode int, elapsed time.Duration, answer answeredBy, reqSize, respSize int64) {
 	accessLog := &models.AccessLog{
 		TenantID:       tenantID,
 		Endpoint:       endpoint,
@@ -284,6 +539,16 @@ func (h *Handler) logAccess(ctx context.Context, tenantID int, endpoint, method
 		ResponseTimeMs: int(elapsed.Milliseconds()),
 		RequestSize:    reqSize,
 		ResponseSize:   respSize,
+		Backend:        answer.backend,
+		Provider:       answer.provider,
+	}
+	if answer.fallbackStep > 0 {
+		step := answer.fallbackStep
+		accessLog.FallbackStep = &step
+	}
+	if answer.ttfb > 0 {
+		ttfbMs := int(answer.ttfb.Milliseconds())
+		accessLog.TTFBMs = &ttfbMs
 	}
 	go h.db.LogAccess(ctx, accessLog)
 }
@@ -295,27 +560,111 @@ func min(a, b int) int {
 	return b
 }
 
+// responseRecorder forwards a backend response to the client while keeping
+// a copy of it. A response that may be retried is held back instead, until
+// the retry loop either discards it or releases it to the client.
 type responseRecorder struct {
 	http.ResponseWriter
+	header        http.Header
 	statusCode    int
 	size          int
 	body          *bytes.Buffer
 	headerWritten bool
+
+	mayRetry    bool  // hold back retryable responses
+	mayFallback bool  // hold back 429 and 5xx responses a fallback may answer instead
+	held        bool  // the response was held back and hasn't reached the client
+	proxyErr    error // why the reverse proxy failed, if it did
+
+	start       time.Time
+	firstByte   time.Duration // time to the first body byte, zero until then
+	flushWrites bool          // the client asked for a stream, flush every write
+}
+
+func newResponseRecorder(w http.ResponseWriter, stream bool, start time.Time) *responseRecorder {
+	return &responseRecorder{
+		ResponseWriter: w,
+		header:         make(http.Header),
+		statusCode:     http.StatusOK,
+		body:           &bytes.Buffer{},
+		start:          start,
+		flushWrites:    stream,
+	}
+}
+
+// Header is this attempt's own header map, copied to the client's on commit.
+func (r *responseRecorder) Header() http.Header {
+	return r.header
 }
 
 func (r *responseRecorder) WriteHeader(statusCode int) {
-	if !r.headerWritten {
-		r.statusCode = statusCode
-		r.ResponseWriter.WriteHeader(statusCode)
-		r.headerWritten = true
+	if r.headerWritten {
+		return
 	}
+	r.statusCode = statusCode
+	r.headerWritten = true
+
+	if (r.mayRetry && isRetryable(statusCode, r.header, r.proxyErr)) || (r.mayFallback && fallbackWorthy(statusCode)) {
+		r.held = true
+		return
+	}
+	r.commit()
+}
+
+func (r *responseRecorder) commit() {
+	dst := r.ResponseWriter.Header()
+	for name, values := range r.header {
+		dst[name] = values
+	}
+	r.ResponseWriter.WriteHeader(r.statusCode)
 }
 
 func (r *responseRecorder) Write(b []byte) (int, error) {
+	if !r.headerWritten {
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.held {
+		return r.body.Write(b)
+	}
+
+	if r.firstByte == 0 && len(b) > 0 {
+		r.firstByte = time.Since(r.start)
+	}
 	size, err := r.ResponseWriter.Write(b)
 	r.size += size
-	if r.body != nil {
-		r.body.Write(b)
+	r.body.Write(b[:size])
+	if r.flushWrites && err == nil {
+		r.Flush()
 	}
 	return size, err
 }
+
+// release sends a held-back response to the client after all.
+func (r *responseRecorder) release() {
+	if !r.held {
+		return
+	}
+	r.held = false
+	r.commit()
+
+	held := r.body.Bytes()
+	r.body = &bytes.Buffer{}
+	r.Write(held)
+}
+
+// Flush sends buffered bytes to the client right away. The reverse proxy
+// calls it after every chunk of an event stream or unsized response.
+func (r *responseRecorder) Flush() {
+	if r.held {
+		return
+	}
+	if !r.headerWritten {
+		r.WriteHeader(http.StatusOK)
+	}
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the client connection.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryOptions controls how failed backend calls are retried.
+type RetryOptions struct {
+	MaxRetries int           // retries after the first attempt, 0 disables retrying
+	BaseDelay  time.Duration // backoff before the first retry, doubled for each one after
+	MaxDelay   time.Duration // cap on backoff, and on any Retry-After the gateway will honor
+
+	// Each tenant may retry BudgetRatio times per request on average, with
+	// up to BudgetBurst retries saved up, so a failing backend isn't hammered
+	BudgetRatio float64
+	BudgetBurst float64
+}
+
+// isRetryable reports whether a response may be retried without risking a
+// duplicate side effect: the backend was never reached, or said it didn't
+// handle the request.
+func isRetryable(statusCode int, header http.Header, proxyErr error) bool {
+	if proxyErr != nil {
+		return isConnectError(proxyErr)
+	}
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusTooManyRequests:
+		return header.Get("Retry-After") != ""
+	}
+	return false
+}
+
+// isConnectError reports whether err happened before a connection to the
+// backend was established.
+func isConnectError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryDelay returns how long to wait before retry number attempt+1: the
+// exponential backoff with jitter, or the backend's Retry-After if longer.
+// It returns false if the backend asks to wait longer than MaxDelay.
+func (o RetryOptions) retryDelay(attempt int, header http.Header) (time.Duration, bool) {
+	delay := o.BaseDelay << attempt
+	if delay <= 0 || delay > o.MaxDelay {
+		delay = o.MaxDelay
+	}
+	// Equal jitter: somewhere between half and all of the backoff
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+
+	if retryAfter, ok := parseRetryAfter(header.Get("Retry-After")); ok {
+		if retryAfter > o.MaxDelay {
+			return 0, false
+		}
+		delay = max(delay, retryAfter)
+	}
+	return delay, true
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(0, time.Until(at)), true
+	}
+	return 0, false
+}
+
+// sleepCtx waits for d, returning false if ctx ends first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// retryBudget is a per-tenant token bucket of retries, kept in memory per
+// gateway instance. Requests deposit tokens and retries spend them.
+type retryBudget struct {
+	ratio float64
+	burst float64
+
+	mu      sync.Mutex
+	tenants map[int]float64
+}
+
+func newRetryBudget(ratio, burst float64) *retryBudget {
+	return &retryBudget{ratio: ratio, burst: burst, tenants: make(map[int]float64)}
+}
+
+func (b *retryBudget) deposit(tenantID int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tokens, ok := b.tenants[tenantID]
+	if !ok {
+		tokens = b.burst
+	}
+	b.tenants[tenantID] = math.Min(b.burst, tokens+b.ratio)
+}
+
+// withdraw spends one retry, returning false when the tenant has none left.
+func (b *retryBudget) withdraw(tenantID int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tokens, ok := b.tenants[tenantID]
+	if !ok {
+		tokens = b.burst
+	}
+	if tokens < 1 {
+		return false
+	}
+	b.tenants[tenantID] = tokens - 1
+	return true
+}
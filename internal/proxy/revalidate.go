@@ -0,0 +1,157 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/balancer"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/cache"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/providers"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/vault"
+)
+
+// How long a background refresh may take, including holding the fill lock
+const revalidateTimeout = 60 * time.Second
+
+// revalidate re-sends a request that was answered by a stale entry and
+// refreshes that entry with the backend's response. Only one refresh per entry
+// runs at a time, in this process and across instances.
+func (h *Handler) revalidate(tenant *models.Tenant, policy *models.TenantModelPolicy, r *http.Request, body []byte, hit *cache.Hit) {
+	// A semantic hit was cached for another prompt, which this request doesn't ask
+	if hit.Semantic {
+		return
+	}
+	// Shared entries are refreshed in their namespace, if the tenant writes to it
+	if !h.semanticCache.Refreshable(r.Context(), tenant.ID, hit) {
+		return
+	}
+	key := fmt.Sprintf("%d:%s", tenant.ID, hit.Entry.PromptHash)
+	if hit.Entry.NamespaceID != nil {
+		key = fmt.Sprintf("namespace:%d:%s", *hit.Entry.NamespaceID, hit.Entry.PromptHash)
+	}
+	if _, running := h.refreshing.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+
+	// The request is done with once the handler returns, so copy what we need now
+	var target balancer.Target
+	var upstream *backend
+	var req *http.Request
+	var translator providers.Translator
+	done := func(time.Duration) {}
+	rt, err := h.routeFor(r.Context(), tenant, policy, requestModel(body))
+	if err == nil {
+		target, done = h.pick(rt.pool, r)
+		upstream, err = h.backends.get(target.URL)
+	}
+	if err == nil {
+		var translated *http.Request
+		if translated, body, err = translateRequest(r, rt.format, body); err == nil {
+			translator = translatorFrom(translated.Context())
+			req, err = refreshRequest(upstream.target, translated, body)
+		}
+	}
+	if err == nil {
+		var creds []vault.Credential
+		if creds, err = h.vault.Credentials(r.Context(), tenant.ID); err == nil {
+			authorize(req, upstream.target.String(), creds)
+		}
+	}
+	if err != nil {
+		done(0)
+		h.refreshing.Delete(key)
+		log.Printf("⚠️  Can't refresh stale entry for tenant %d: %v", tenant.ID, err)
+		return
+	}
+
+	go func() {
+		defer h.refreshing.Delete(key)
+		defer done(0)
+
+		ctx, cancel := context.WithTimeout(context.Background(), revalidateTimeout)
+		defer cancel()
+
+		release, acquired := h.semanticCache.AcquireFillLock(ctx, tenant.ID, hit.Entry.PromptHash, revalidateTimeout)
+		if !acquired {
+			return // another instance is already refreshing it
+		}
+		defer release()
+
+		// Refreshes count towards the backend's circuit and health like
+		// proxied requests, and aren't sent while its circuit is open
+		report, _, allowed := h.breakers.Get(target.URL).Allow()
+		if !allowed {
+			log.Printf("⚡ Circuit open for %s, not refreshing stale entry for tenant %d", target.URL, tenant.ID)
+			return
+		}
+
+		// Refreshes share the backend's connection pool with proxied requests
+		client := &http.Client{Transport: upstream.transport}
+		start := time.Now()
+		resp, err := client.Do(req.WithContext(ctx))
+		elapsed := time.Since(start)
+		done(elapsed)
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+		}
+		o := callOutcome(status, err)
+		report(o, elapsed)
+		h.reportOutcome(target.URL, o)
+		if err != nil {
+			log.Printf("⚠️  Refresh of stale entry for tenant %d failed: %v", tenant.ID, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			log.Printf("⚠️  Refresh of stale entry for tenant %d failed: status %d", tenant.ID, resp.StatusCode)
+			return
+		}
+		if translator != nil {
+			if body, err = translateBody(translator, resp.StatusCode, resp.Header, body); err != nil {
+				log.Printf("⚠️  Refresh of stale entry for tenant %d failed: %v", tenant.ID, err)
+				return
+			}
+		}
+		fresh, ok := captureResponse(resp.StatusCode, resp.Header, body)
+		if !ok || len(fresh.Body) == 0 {
+			return
+		}
+
+		if err := h.semanticCache.RefreshCachedResponse(ctx, tenant.ID, hit, fresh); err != nil {
+			log.Printf("❌ Failed to refresh cached response: %v", err)
+			return
+		}
+		log.Printf("♻️  Stale entry refreshed for tenant %d", tenant.ID)
+	}()
+}
+
+// refreshRequest builds the backend request for a background refresh, the
+// same way the reverse proxy would forward r.
+func refreshRequest(backendURL *url.URL, r *http.Request, body []byte) (*http.Request, error) {
+	target := *backendURL
+	target.Path = strings.TrimSuffix(backendURL.Path, "/") + "/" + strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api"), "/")
+	target.RawQuery = r.URL.RawQuery
+
+	req, err := http.NewRequest(r.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header = r.Header.Clone()
+	// Let the transport negotiate compression so the body arrives decoded
+	req.Header.Del("Accept-Encoding")
+	req.Header.Del("Connection")
+
+	return req, nil
+}
@@ -0,0 +1,285 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// isStreamRequest reports whether the client asked for a streamed ("stream": true) response.
+func isStreamRequest(bodyBytes []byte) bool {
+	var reqBody struct {
+		Stream bool `json:"stream"`
+	}
+	if err := json.Unmarshal(bodyBytes, &reqBody); err != nil {
+		return false
+	}
+	return reqBody.Stream
+}
+
+func isEventStream(header http.Header) bool {
+	return strings.HasPrefix(header.Get("Content-Type"), "text/event-stream")
+}
+
+// streamChunk is one OpenAI-style streaming event. Chat completions carry a
+// delta per choice, legacy completions carry text.
+type streamChunk struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta *struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"delta"`
+		Text         *string `json:"text"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage json.RawMessage `json:"usage"`
+}
+
+type assembledChoice struct {
+	role         string
+	content      strings.Builder
+	finishReason *string
+}
+
+// assembleStream turns an OpenAI-style SSE transcript into the equivalent
+// non-streamed response body, which is the form stored in the cache. It
+// returns false for streams it doesn't understand.
+func assembleStream(body []byte) ([]byte, bool) {
+	var first streamChunk
+	var usage json.RawMessage
+	choices := map[int]*assembledChoice{}
+	chat := false
+	events := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, false
+		}
+		if events == 0 {
+			first = chunk
+		}
+		events++
+
+		if len(chunk.Usage) > 0 && string(chunk.Usage) != "null" {
+			usage = chunk.Usage
+		}
+
+		for _, c := range chunk.Choices {
+			choice, ok := choices[c.Index]
+			if !ok {
+				choice = &assembledChoice{role: "assistant"}
+				choices[c.Index] = choice
+			}
+			if c.Delta != nil {
+				chat = true
+				if c.Delta.Role != "" {
+					choice.role = c.Delta.Role
+				}
+				choice.content.WriteString(c.Delta.Content)
+			}
+			if c.Text != nil {
+				choice.content.WriteString(*c.Text)
+			}
+			if c.FinishReason != nil {
+				choice.finishReason = c.FinishReason
+			}
+		}
+	}
+	if scanner.Err() != nil || events == 0 || len(choices) == 0 {
+		return nil, false
+	}
+
+	result := map[string]interface{}{
+		"id":      first.ID,
+		"created": first.Created,
+		"model":   first.Model,
+	}
+	if usage != nil {
+		result["usage"] = usage
+	}
+
+	out := make([]map[string]interface{}, len(choices))
+	for i := range out {
+		choice, ok := choices[i]
+		if !ok {
+			return nil, false
+		}
+		out[i] = map[string]interface{}{
+			"index":         i,
+			"finish_reason": choice.finishReason,
+		}
+		if chat {
+			out[i]["message"] = map[string]string{"role": choice.role, "content": choice.content.String()}
+		} else {
+			out[i]["text"] = choice.content.String()
+		}
+	}
+	result["choices"] = out
+
+	if chat {
+		result["object"] = "chat.completion"
+	} else {
+		result["object"] = "text_completion"
+	}
+
+	assembled, err := json.Marshal(result)
+	if err != nil {
+		return nil, false
+	}
+	return assembled, true
+}
+
+// completion is the subset of a non-streamed OpenAI response needed to replay it as a stream.
+type completion struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index   int `json:"index"`
+		Message *struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"message"`
+		Text         *string `json:"text"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage json.RawMessage `json:"usage"`
+}
+
+// replayStream writes a cached response as a text/event-stream, splitting
+// each choice's content into chunks of chunkSize runes and waiting pacing
+// between events. Bodies that aren't OpenAI completions are sent as one event.
+func replayStream(ctx context.Context, w http.ResponseWriter, body []byte, chunkSize int, pacing time.Duration) error {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	send := func(v interface{}) error {
+		var data []byte
+		switch v := v.(type) {
+		case []byte:
+			data = v
+		default:
+			data, _ = json.Marshal(v)
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if pacing > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(pacing):
+			}
+		}
+		return nil
+	}
+
+	var c completion
+	if err := json.Unmarshal(body, &c); err != nil || len(c.Choices) == 0 {
+		if err := send(body); err != nil {
+			return err
+		}
+		return send([]byte("[DONE]"))
+	}
+
+	chat := c.Object == "chat.completion" || c.Choices[0].Message != nil
+	object := "text_completion"
+	if chat {
+		object = "chat.completion.chunk"
+	}
+	event := func(index int, delta map[string]string, text *string, finishReason *string) map[string]interface{} {
+		choice := map[string]interface{}{"index": index, "finish_reason": finishReason}
+		if chat {
+			choice["delta"] = delta
+		} else {
+			choice["text"] = *text
+		}
+		return map[string]interface{}{
+			"id":      c.ID,
+			"object":  object,
+			"created": c.Created,
+			"model":   c.Model,
+			"choices": []interface{}{choice},
+		}
+	}
+
+	if chunkSize <= 0 {
+		chunkSize = 20
+	}
+
+	for _, choice := range c.Choices {
+		var content, role string
+		switch {
+		case choice.Message != nil:
+			content, role = choice.Message.Content, choice.Message.Role
+		case choice.Text != nil:
+			content = *choice.Text
+		}
+		if role == "" {
+			role = "assistant"
+		}
+
+		if chat {
+			if err := send(event(choice.Index, map[string]string{"role": role, "content": ""}, nil, nil)); err != nil {
+				return err
+			}
+		}
+
+		runes := []rune(content)
+		for start := 0; start < len(runes); start += chunkSize {
+			piece := string(runes[start:min(start+chunkSize, len(runes))])
+			if err := send(event(choice.Index, map[string]string{"content": piece}, &piece, nil)); err != nil {
+				return err
+			}
+		}
+
+		empty := ""
+		if err := send(event(choice.Index, map[string]string{}, &empty, choice.FinishReason)); err != nil {
+			return err
+		}
+	}
+
+	if len(c.Usage) > 0 && string(c.Usage) != "null" {
+		if err := send(map[string]interface{}{
+			"id":      c.ID,
+			"object":  object,
+			"created": c.Created,
+			"model":   c.Model,
+			"choices": []interface{}{},
+			"usage":   c.Usage,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return send([]byte("[DONE]"))
+}
@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/providers"
+)
+
+// translatorKey carries a request's translator to the reverse proxy, which
+// translates the response back with it.
+type translatorKey struct{}
+
+func translatorFrom(ctx context.Context) providers.Translator {
+	t, _ := ctx.Value(translatorKey{}).(providers.Translator)
+	return t
+}
+
+// translateRequest rewrites a client request in OpenAI's format for a
+// backend speaking format. Requests that need no translation are returned
+// as they are.
+func translateRequest(r *http.Request, format string, body []byte) (*http.Request, []byte, error) {
+	t := providers.For(providers.Format(format))
+	if t == nil {
+		return r, body, nil
+	}
+	translated, err := t.Request(r.URL.Path, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if translated == nil {
+		return r, body, nil
+	}
+
+	req := r.Clone(context.WithValue(r.Context(), translatorKey{}, t))
+	req.URL.Path = translated.Path
+	req.URL.RawPath = ""
+	if translated.RawQuery != "" {
+		if req.URL.RawQuery != "" {
+			req.URL.RawQuery += "&"
+		}
+		req.URL.RawQuery += translated.RawQuery
+	}
+	for name, values := range translated.Header {
+		if req.Header.Get(name) == "" {
+			req.Header[name] = values
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// The response is rewritten, so let the transport decode it
+	req.Header.Del("Accept-Encoding")
+	req.Body = io.NopCloser(bytes.NewReader(translated.Body))
+	req.ContentLength = int64(len(translated.Body))
+
+	return req, translated.Body, nil
+}
+
+// translateResponse is the reverse proxy's ModifyResponse: it turns a
+// translated request's response back into OpenAI's format. Event streams
+// are translated as they arrive.
+func translateResponse(resp *http.Response) error {
+	t := translatorFrom(resp.Request.Context())
+	if t == nil {
+		return nil
+	}
+
+	if resp.StatusCode < 400 && isEventStream(resp.Header) {
+		src := resp.Body
+		pr, pw := io.Pipe()
+		go func() {
+			defer src.Close()
+			pw.CloseWithError(t.Stream(src, pw))
+		}()
+		resp.Body = pr
+		resp.ContentLength = -1
+		resp.Header.Del("Content-Length")
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	if body, err = decodeBody(resp.Header.Get("Content-Encoding"), body); err != nil {
+		return err
+	}
+	resp.Header.Del("Content-Encoding")
+	if body, err = translateBody(t, resp.StatusCode, resp.Header, body); err != nil {
+		return err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	if resp.StatusCode < 400 {
+		resp.Header.Set("Content-Type", "application/json")
+	}
+	return nil
+}
+
+// translateBody translates a complete response body.
+func translateBody(t providers.Translator, statusCode int, header http.Header, body []byte) ([]byte, error) {
+	if statusCode >= 400 {
+		return t.Error(body), nil
+	}
+	if isEventStream(header) {
+		var buf bytes.Buffer
+		err := t.Stream(bytes.NewReader(body), &buf)
+		return buf.Bytes(), err
+	}
+	return t.Response(body)
+}
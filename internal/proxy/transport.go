@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransportOptions tunes the connections kept to each backend.
+type TransportOptions struct {
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration // zero waits as long as the request timeout allows
+	IdleConnTimeout       time.Duration
+	MaxIdleConnsPerHost   int
+	MaxConnsPerHost       int // zero means unlimited
+	HTTP2                 bool
+}
+
+// backend is the reverse proxy and connection pool for one backend URL.
+type backend struct {
+	target    *url.URL
+	proxy     *httputil.ReverseProxy
+	transport *http.Transport
+}
+
+// backendRegistry hands out one backend per backend URL, built on first use,
+// so connections are reused across requests and tenants. A backend is closed
+// once no tenant points at its URL anymore.
+type backendRegistry struct {
+	opts TransportOptions
+
+	mu       sync.Mutex
+	backends map[string]*backend
+	tenants  map[int][]string // tenant ID -> backend URLs it last used
+}
+
+func newBackendRegistry(opts TransportOptions) *backendRegistry {
+	return &backendRegistry{
+		opts:     opts,
+		backends: make(map[string]*backend),
+		tenants:  make(map[int][]string),
+	}
+}
+
+// use records the backend URLs a tenant currently points at, dropping the
+// backends of URLs it no longer uses.
+func (br *backendRegistry) use(tenantID int, urls []string) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	previous := br.tenants[tenantID]
+	if slices.Equal(previous, urls) {
+		return
+	}
+	if previous != nil {
+		log.Printf("🔁 Backends for tenant %d changed from %v to %v", tenantID, previous, urls)
+	}
+	br.tenants[tenantID] = urls
+	for _, u := range previous {
+		if !slices.Contains(urls, u) {
+			br.release(u)
+		}
+	}
+}
+
+// get returns the backend for a URL.
+func (br *backendRegistry) get(rawURL string) (*backend, error) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	b, ok := br.backends[rawURL]
+	if !ok {
+		target, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		if target.Scheme == "" || target.Host == "" {
+			return nil, errors.New("backend URL must be absolute")
+		}
+		b = br.newBackend(target)
+		br.backends[rawURL] = b
+	}
+
+	return b, nil
+}
+
+// release closes a backend nobody points at anymore. Callers hold br.mu.
+func (br *backendRegistry) release(rawURL string) {
+	for _, urls := range br.tenants {
+		if slices.Contains(urls, rawURL) {
+			return
+		}
+	}
+	if b, ok := br.backends[rawURL]; ok {
+		b.transport.CloseIdleConnections()
+		delete(br.backends, rawURL)
+	}
+}
+
+func (br *backendRegistry) newBackend(target *url.URL) *backend {
+	dialer := &net.Dialer{
+		Timeout:   br.opts.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     br.opts.HTTP2,
+		TLSHandshakeTimeout:   br.opts.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: br.opts.ResponseHeaderTimeout,
+		IdleConnTimeout:       br.opts.IdleConnTimeout,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   br.opts.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       br.opts.MaxConnsPerHost,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if !br.opts.HTTP2 {
+		// A non-nil empty map turns HTTP/2 off for TLS backends
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		authorize(req, target.String(), credentialsFrom(req.Context()))
+	}
+	proxy.Transport = transport
+	proxy.ErrorHandler = proxyErrorHandler
+	proxy.ModifyResponse = translateResponse
+
+	return &backend{target: target, proxy: proxy, transport: transport}
+}
+
+func proxyErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("❌ Proxy error: %v", err)
+	if recorder, ok := w.(*responseRecorder); ok {
+		recorder.proxyErr = err
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		http.Error(w, "Backend timeout - request took too long", http.StatusGatewayTimeout)
+	} else if strings.Contains(err.Error(), "no such host") {
+		http.Error(w, "Backend DNS resolution failed", http.StatusBadGateway)
+	} else if strings.Contains(err.Error(), "connection refused") {
+		http.Error(w, "Backend connection refused", http.StatusBadGateway)
+	} else {
+		http.Error(w, "Bad Gateway: "+err.Error(), http.StatusBadGateway)
+	}
+}
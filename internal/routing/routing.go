@@ -0,0 +1,105 @@
+// Package routing keeps a short-lived in-memory copy of where each tenant's
+// requests may go, so the proxy doesn't read it from Postgres per request.
+package routing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/balancer"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/db"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// Routes are a tenant's enabled pool backends, fallback chain and model
+// policy. They are shared by concurrent requests and must not be modified.
+type Routes struct {
+	Targets   []balancer.Target // the pool, or backend_url alone when it's empty
+	Fallbacks []models.TenantFallback
+	Policy    *models.TenantModelPolicy // nil if the tenant has none
+	URLs      []string                  // every backend URL above
+}
+
+// Store caches Routes per tenant for TTL. The admin API forgets a tenant's
+// routes when it changes them; changes made through another gateway
+// instance show up once the cache expires.
+type Store struct {
+	db  *db.DB
+	ttl time.Duration
+
+	mu      sync.Mutex
+	tenants map[int]cachedRoutes
+}
+
+type cachedRoutes struct {
+	routes     *Routes
+	backendURL string // the tenant's backend_url when they were loaded
+	loadedAt   time.Time
+}
+
+func New(database *db.DB, ttl time.Duration) *Store {
+	return &Store{db: database, ttl: ttl, tenants: make(map[int]cachedRoutes)}
+}
+
+// For returns a tenant's routes.
+func (s *Store) For(ctx context.Context, tenant *models.Tenant) (*Routes, error) {
+	s.mu.Lock()
+	cached, ok := s.tenants[tenant.ID]
+	s.mu.Unlock()
+	if ok && cached.backendURL == tenant.BackendURL && time.Since(cached.loadedAt) < s.ttl {
+		return cached.routes, nil
+	}
+
+	backends, err := s.db.ListTenantBackends(ctx, tenant.ID, true)
+	if err != nil {
+		return nil, err
+	}
+	fallbacks, err := s.db.ListTenantFallbacks(ctx, tenant.ID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := s.db.GetTenantModelPolicy(ctx, tenant.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		policy = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	routes := &Routes{Fallbacks: fallbacks, Policy: policy}
+	for _, b := range backends {
+		routes.Targets = append(routes.Targets, balancer.Target{ID: b.ID, URL: b.URL, Weight: b.Weight})
+	}
+	if len(routes.Targets) == 0 {
+		routes.Targets = append(routes.Targets, balancer.Target{URL: tenant.BackendURL, Weight: 1})
+	}
+	for _, t := range routes.Targets {
+		routes.URLs = append(routes.URLs, t.URL)
+	}
+	for _, f := range fallbacks {
+		routes.URLs = append(routes.URLs, f.URL)
+	}
+	if policy != nil {
+		for _, mr := range policy.Routes {
+			routes.URLs = append(routes.URLs, mr.URL)
+		}
+	}
+
+	s.mu.Lock()
+	s.tenants[tenant.ID] = cachedRoutes{routes: routes, backendURL: tenant.BackendURL, loadedAt: time.Now()}
+	s.mu.Unlock()
+	return routes, nil
+}
+
+// Forget drops a tenant's cached routes after they changed.
+func (s *Store) Forget(tenantID int) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	delete(s.tenants, tenantID)
+	s.mu.Unlock()
+}
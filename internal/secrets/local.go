@@ -0,0 +1,99 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// LocalKMS is a stand-in for a cloud KMS, backed by a keyring file:
+//
+//	{"primary": "key-2", "keys": {"key-1": "<base64>", "key-2": "<base64>"}}
+//
+// New data keys are wrapped with the primary key. Older keys stay in the
+// keyring so keys they wrapped can still be unwrapped, which lets the master
+// key be rotated by adding a key and making it primary. The file is created
+// with one random key if it doesn't exist.
+type LocalKMS struct {
+	primary string
+	keys    map[string][]byte
+}
+
+type keyring struct {
+	Primary string            `json:"primary"`
+	Keys    map[string][]byte `json:"keys"`
+}
+
+func NewLocalKMS(path string) (*LocalKMS, error) {
+	if path == "" {
+		return nil, errors.New("local key management needs a keyring path")
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return createKeyring(path)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var ring keyring
+	if err := json.Unmarshal(data, &ring); err != nil {
+		return nil, fmt.Errorf("invalid keyring %s: %w", path, err)
+	}
+	if _, ok := ring.Keys[ring.Primary]; !ok {
+		return nil, fmt.Errorf("keyring %s has no primary key %q", path, ring.Primary)
+	}
+	for id, key := range ring.Keys {
+		if len(id) > 255 {
+			return nil, fmt.Errorf("keyring %s: key ID %q is too long", path, id)
+		}
+		if _, err := newGCM(key); err != nil {
+			return nil, fmt.Errorf("keyring %s: key %q: %w", path, id, err)
+		}
+	}
+
+	return &LocalKMS{primary: ring.Primary, keys: ring.Keys}, nil
+}
+
+func createKeyring(path string) (*LocalKMS, error) {
+	key, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	ring := keyring{Primary: "key-1", Keys: map[string][]byte{"key-1": key}}
+	data, _ := json.MarshalIndent(ring, "", "  ")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+
+	return &LocalKMS{primary: ring.Primary, keys: ring.Keys}, nil
+}
+
+// WrapKey seals key with the primary key, prefixed with that key's ID.
+func (k *LocalKMS) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	sealed, err := Seal(k.keys[k.primary], key, dataKeyAAD)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped := make([]byte, 0, 1+len(k.primary)+len(sealed))
+	wrapped = append(wrapped, byte(len(k.primary)))
+	wrapped = append(wrapped, k.primary...)
+	return append(wrapped, sealed...), nil
+}
+
+func (k *LocalKMS) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	if len(wrapped) == 0 || len(wrapped) < 1+int(wrapped[0]) {
+		return nil, errors.New("wrapped key is too short")
+	}
+
+	id := string(wrapped[1 : 1+int(wrapped[0])])
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("key %q is not in the keyring", id)
+	}
+	return Open(key, wrapped[1+int(wrapped[0]):], dataKeyAAD)
+}
@@ -0,0 +1,26 @@
+package secrets
+
+import "context"
+
+// Bound into every wrapped key, so wrapped keys can't be confused with other sealed values
+var dataKeyAAD = []byte("gateway-data-key")
+
+// MasterKey wraps data keys with a single key taken from configuration.
+type MasterKey struct {
+	key []byte
+}
+
+func NewMasterKey(key []byte) (*MasterKey, error) {
+	if _, err := newGCM(key); err != nil {
+		return nil, err
+	}
+	return &MasterKey{key: key}, nil
+}
+
+func (m *MasterKey) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	return Seal(m.key, key, dataKeyAAD)
+}
+
+func (m *MasterKey) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	return Open(m.key, wrapped, dataKeyAAD)
+}
@@ -0,0 +1,106 @@
+// Package secrets wraps per-tenant data keys with a master key, and seals
+// values with AES-GCM.
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// KeySize is the size of data and master keys, AES-256
+const KeySize = 32
+
+// KeyWrapper encrypts and decrypts data keys. Data keys are only ever stored
+// wrapped.
+type KeyWrapper interface {
+	WrapKey(ctx context.Context, key []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// Options selects and configures a KeyWrapper.
+type Options struct {
+	Provider    string // "master", "local" or "none"; empty picks master when MasterKey is set
+	MasterKey   string // base64-encoded 32-byte key for the master provider
+	KeyringPath string // keyring file for the local provider
+}
+
+// New returns the configured KeyWrapper, or nil when key management is disabled.
+func New(opts Options) (KeyWrapper, error) {
+	provider := opts.Provider
+	if provider == "" {
+		provider = "none"
+		if opts.MasterKey != "" {
+			provider = "master"
+		}
+	}
+
+	switch provider {
+	case "none":
+		return nil, nil
+	case "master":
+		key, err := base64.StdEncoding.DecodeString(opts.MasterKey)
+		if err != nil {
+			return nil, fmt.Errorf("master key is not valid base64: %w", err)
+		}
+		return NewMasterKey(key)
+	case "local":
+		return NewLocalKMS(opts.KeyringPath)
+	default:
+		return nil, fmt.Errorf("unknown key management provider %q", provider)
+	}
+}
+
+// GenerateKey returns a new random key.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Seal encrypts plaintext with AES-GCM. The nonce is prepended to the result,
+// and aad must be passed to Open unchanged.
+func Seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// Open decrypts a value produced by Seal.
+func Open(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("sealed value is too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
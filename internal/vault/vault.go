@@ -0,0 +1,211 @@
+// Package vault keeps tenants' upstream provider credentials sealed at rest
+// and adds them to backend requests.
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/db"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/models"
+	"github.com/HanTheDev/multi-tenant-api-gateway/internal/secrets"
+)
+
+// ErrNoKeyManagement is returned when storing a credential without a key
+// management provider.
+var ErrNoKeyManagement = errors.New("upstream credentials require a key management provider")
+
+// Where a credential goes in the upstream request
+const (
+	InHeader = "header"
+	InQuery  = "query"
+)
+
+// Credential is an opened credential, ready to be injected.
+type Credential struct {
+	BackendURL string
+	Location   string
+	Param      string
+	Value      string // prefix and secret
+}
+
+// Vault seals credentials and caches the opened ones per tenant for TTL, so
+// the key wrapper isn't called on every request. Changes made through
+// another gateway instance show up once the cache expires.
+type Vault struct {
+	db   *db.DB
+	keys secrets.KeyWrapper // nil disables the vault
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	tenants map[int]cachedCredentials
+}
+
+type cachedCredentials struct {
+	creds    []Credential
+	loadedAt time.Time
+}
+
+func New(database *db.DB, keys secrets.KeyWrapper, ttl time.Duration) *Vault {
+	return &Vault{db: database, keys: keys, ttl: ttl, tenants: make(map[int]cachedCredentials)}
+}
+
+// Enabled reports whether credentials can be stored.
+func (v *Vault) Enabled() bool {
+	return v != nil && v.keys != nil
+}
+
+// Bound into every sealed secret, so a secret can't be moved to another
+// tenant or credential, nor pointed at another backend, header or parameter
+// by editing its row
+func credentialAAD(cred *models.TenantCredential) []byte {
+	return []byte(strings.Join([]string{
+		"tenant-credential", strconv.Itoa(cred.TenantID), cred.Name, cred.BackendURL, cred.Location, cred.Param,
+	}, "\x00"))
+}
+
+// Seal encrypts secret into cred with a new data key of its own.
+func (v *Vault) Seal(ctx context.Context, cred *models.TenantCredential, secret string) error {
+	if !v.Enabled() {
+		return ErrNoKeyManagement
+	}
+
+	key, err := secrets.GenerateKey()
+	if err != nil {
+		return err
+	}
+	wrapped, err := v.keys.WrapKey(ctx, key)
+	if err != nil {
+		return fmt.Errorf("wrap credential key: %w", err)
+	}
+	sealed, err := secrets.Seal(key, []byte(secret), credentialAAD(cred))
+	if err != nil {
+		return err
+	}
+
+	cred.WrappedKey = wrapped
+	cred.SealedSecret = sealed
+	cred.SecretHint = hint(secret)
+	return nil
+}
+
+// Reseal seals the secret of stored again for cred, the same credential with
+// a new name or injection rule, which are bound into the sealed secret.
+func (v *Vault) Reseal(ctx context.Context, stored, cred *models.TenantCredential) error {
+	if !v.Enabled() {
+		return ErrNoKeyManagement
+	}
+	secret, err := v.open(ctx, stored)
+	if err != nil {
+		return err
+	}
+	return v.Seal(ctx, cred, string(secret))
+}
+
+func (v *Vault) open(ctx context.Context, cred *models.TenantCredential) ([]byte, error) {
+	key, err := v.keys.UnwrapKey(ctx, cred.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap key of credential %q: %w", cred.Name, err)
+	}
+	secret, err := secrets.Open(key, cred.SealedSecret, credentialAAD(cred))
+	if err != nil {
+		return nil, fmt.Errorf("open credential %q: %w", cred.Name, err)
+	}
+	return secret, nil
+}
+
+// hint shows the end of a secret, or nothing of short ones.
+func hint(secret string) string {
+	if len(secret) < 12 {
+		return "****"
+	}
+	return "..." + secret[len(secret)-4:]
+}
+
+// Credentials returns a tenant's opened credentials.
+func (v *Vault) Credentials(ctx context.Context, tenantID int) ([]Credential, error) {
+	if !v.Enabled() {
+		return nil, nil
+	}
+
+	v.mu.Lock()
+	cached, ok := v.tenants[tenantID]
+	v.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < v.ttl {
+		return cached.creds, nil
+	}
+
+	stored, err := v.db.ListTenantCredentials(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	creds := make([]Credential, 0, len(stored))
+	for _, cred := range stored {
+		secret, err := v.open(ctx, &cred)
+		if err != nil {
+			return nil, err
+		}
+		creds = append(creds, Credential{
+			BackendURL: cred.BackendURL,
+			Location:   cred.Location,
+			Param:      cred.Param,
+			Value:      cred.Prefix + string(secret),
+		})
+	}
+
+	v.mu.Lock()
+	v.tenants[tenantID] = cachedCredentials{creds: creds, loadedAt: time.Now()}
+	v.mu.Unlock()
+	return creds, nil
+}
+
+// Forget drops a tenant's cached credentials after they changed.
+func (v *Vault) Forget(tenantID int) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	delete(v.tenants, tenantID)
+	v.mu.Unlock()
+}
+
+// StripClientAuth removes the client's credentials for the gateway, which
+// must never reach a backend.
+func StripClientAuth(header http.Header) {
+	header.Del("Authorization")
+	header.Del("Proxy-Authorization")
+}
+
+// Inject adds the credentials that apply to backendURL to an upstream
+// request, replacing whatever the client sent under the same name.
+func Inject(req *http.Request, backendURL string, creds []Credential) {
+	var query url.Values
+	for _, cred := range creds {
+		if !sameURL(cred.BackendURL, backendURL) {
+			continue
+		}
+		switch cred.Location {
+		case InQuery:
+			if query == nil {
+				query = req.URL.Query()
+			}
+			query.Set(cred.Param, cred.Value)
+		default:
+			req.Header.Set(cred.Param, cred.Value)
+		}
+	}
+	if query != nil {
+		req.URL.RawQuery = query.Encode()
+	}
+}
+
+func sameURL(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
@@ -0,0 +1,19 @@
+-- Explicit expiry for cache entries
+ALTER TABLE semantic_cache ADD COLUMN expires_at TIMESTAMP;
+
+CREATE INDEX idx_cache_expires_at ON semantic_cache(expires_at);
+
+-- Prompt hashes are only unique within a tenant
+ALTER TABLE semantic_cache DROP CONSTRAINT semantic_cache_prompt_hash_key;
+ALTER TABLE semantic_cache ADD CONSTRAINT semantic_cache_tenant_prompt_hash_key UNIQUE (tenant_id, prompt_hash);
+
+CREATE INDEX idx_cache_tenant_last_accessed ON semantic_cache(tenant_id, last_accessed);
+
+-- Per-tenant cache settings (NULL columns fall back to the gateway defaults)
+CREATE TABLE tenant_cache_settings (
+    tenant_id INTEGER PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    ttl_seconds INTEGER,
+    max_entries INTEGER,
+    eviction_policy VARCHAR(10),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
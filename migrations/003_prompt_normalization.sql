@@ -0,0 +1,5 @@
+-- Normalized form of each cached prompt, as hashed and embedded
+ALTER TABLE semantic_cache ADD COLUMN normalized_prompt TEXT;
+
+-- Per-tenant regex rewrite rules: [{"pattern": "...", "replacement": "..."}]
+ALTER TABLE tenant_cache_settings ADD COLUMN normalization_rules JSONB;
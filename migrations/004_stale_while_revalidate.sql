@@ -0,0 +1,2 @@
+-- Soft TTL: entries older than this are served as stale while being refreshed
+ALTER TABLE tenant_cache_settings ADD COLUMN soft_ttl_seconds INTEGER;
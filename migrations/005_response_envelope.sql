@@ -0,0 +1,5 @@
+-- Cached responses keep their status, the upstream headers worth replaying and
+-- the encoding the body arrived in. The body itself is stored decoded.
+ALTER TABLE semantic_cache ADD COLUMN status_code INTEGER NOT NULL DEFAULT 200;
+ALTER TABLE semantic_cache ADD COLUMN response_headers JSONB;
+ALTER TABLE semantic_cache ADD COLUMN content_encoding VARCHAR(20);
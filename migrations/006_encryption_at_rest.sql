@@ -0,0 +1,19 @@
+-- Per-tenant data keys, wrapped by the gateway's master key. Only the active
+-- version encrypts new entries; older versions are kept to read existing rows.
+CREATE TABLE tenant_data_keys (
+    tenant_id INTEGER REFERENCES tenants(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, version)
+);
+
+-- Sealed entries store base64 ciphertext in prompt, normalized_prompt and
+-- response; data_key_version is NULL for plaintext rows. compression is set
+-- when the response was zstd-compressed before sealing or encoding.
+ALTER TABLE semantic_cache ADD COLUMN data_key_version INTEGER;
+ALTER TABLE semantic_cache ADD COLUMN compression VARCHAR(10);
+CREATE INDEX idx_cache_tenant_key_version ON semantic_cache(tenant_id, data_key_version);
+
+ALTER TABLE tenant_cache_settings ADD COLUMN encrypt_at_rest BOOLEAN;
@@ -0,0 +1,29 @@
+-- Every cache hit, with its similarity, so clients can report bad ones
+CREATE TABLE cache_hit_events (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id INTEGER REFERENCES tenants(id) ON DELETE CASCADE,
+    entry_id BIGINT REFERENCES semantic_cache(id) ON DELETE SET NULL,
+    prompt_hash VARCHAR(64) NOT NULL,       -- the request's prompt
+    entry_prompt_hash VARCHAR(64) NOT NULL, -- the entry that answered it
+    similarity DOUBLE PRECISION NOT NULL,
+    semantic BOOLEAN NOT NULL,
+    feedback VARCHAR(10),
+    feedback_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_hit_events_tenant_entry ON cache_hit_events(tenant_id, entry_id, created_at DESC);
+CREATE INDEX idx_hit_events_tenant_created ON cache_hit_events(tenant_id, created_at);
+
+-- Prompt/entry pairs that must not match semantically again
+CREATE TABLE cache_blacklist (
+    tenant_id INTEGER REFERENCES tenants(id) ON DELETE CASCADE,
+    prompt_hash VARCHAR(64) NOT NULL,
+    entry_prompt_hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, prompt_hash, entry_prompt_hash)
+);
+
+-- Raised automatically when semantic hits keep being reported as wrong
+ALTER TABLE tenant_cache_settings ADD COLUMN similarity_threshold DOUBLE PRECISION;
+ALTER TABLE tenant_cache_settings ADD COLUMN threshold_adjusted_at TIMESTAMP;
@@ -0,0 +1,33 @@
+-- Named caches shared between tenants. Tenants only use them after opting in
+-- (tenant_cache_settings.shared_cache) and within their membership.
+CREATE TABLE cache_namespaces (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) UNIQUE NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE cache_namespace_members (
+    namespace_id INTEGER REFERENCES cache_namespaces(id) ON DELETE CASCADE,
+    tenant_id INTEGER REFERENCES tenants(id) ON DELETE CASCADE,
+    can_read BOOLEAN NOT NULL DEFAULT TRUE,
+    can_write BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (namespace_id, tenant_id)
+);
+
+CREATE INDEX idx_namespace_members_tenant ON cache_namespace_members(tenant_id);
+
+ALTER TABLE tenant_cache_settings ADD COLUMN shared_cache BOOLEAN;
+
+-- Shared entries belong to a namespace instead of a tenant; source_tenant_id
+-- records who contributed them
+ALTER TABLE semantic_cache ADD COLUMN namespace_id INTEGER REFERENCES cache_namespaces(id) ON DELETE CASCADE;
+ALTER TABLE semantic_cache ADD COLUMN source_tenant_id INTEGER REFERENCES tenants(id) ON DELETE SET NULL;
+
+ALTER TABLE semantic_cache DROP CONSTRAINT semantic_cache_tenant_prompt_hash_key;
+CREATE UNIQUE INDEX semantic_cache_tenant_prompt_hash_key ON semantic_cache(tenant_id, prompt_hash) WHERE namespace_id IS NULL;
+CREATE UNIQUE INDEX semantic_cache_namespace_prompt_hash_key ON semantic_cache(namespace_id, prompt_hash) WHERE namespace_id IS NOT NULL;
+
+-- Which namespace answered a hit, NULL for the tenant's own cache
+ALTER TABLE cache_hit_events ADD COLUMN namespace_id INTEGER REFERENCES cache_namespaces(id) ON DELETE SET NULL;
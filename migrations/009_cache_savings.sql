@@ -0,0 +1,18 @@
+-- Tokens and cost each hit avoided, from the cached response's usage. cost_saved
+-- is NULL when the model has no known price.
+ALTER TABLE cache_hit_events ADD COLUMN model VARCHAR(100);
+ALTER TABLE cache_hit_events ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE cache_hit_events ADD COLUMN completion_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE cache_hit_events ADD COLUMN cost_saved NUMERIC(14, 6);
+
+-- Per tenant and day totals, kept up to date as hits are recorded
+CREATE TABLE cache_savings_daily (
+    tenant_id INTEGER REFERENCES tenants(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    hits BIGINT NOT NULL DEFAULT 0,
+    priced_hits BIGINT NOT NULL DEFAULT 0,
+    prompt_tokens BIGINT NOT NULL DEFAULT 0,
+    completion_tokens BIGINT NOT NULL DEFAULT 0,
+    cost_saved NUMERIC(14, 6) NOT NULL DEFAULT 0,
+    PRIMARY KEY (tenant_id, day)
+);
@@ -0,0 +1,3 @@
+-- Time until the backend's first body byte reached the client, NULL when the
+-- request was answered without the backend (cache hits, coalesced waiters)
+ALTER TABLE access_logs ADD COLUMN ttfb_ms INTEGER;
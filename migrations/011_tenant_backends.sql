@@ -0,0 +1,19 @@
+-- Backend pools. A tenant with enabled pool backends is load balanced across
+-- them; otherwise its backend_url is used as before.
+CREATE TABLE tenant_backends (
+    id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    url VARCHAR(500) NOT NULL,
+    weight INTEGER NOT NULL DEFAULT 1 CHECK (weight > 0),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, url)
+);
+
+-- round_robin, least_outstanding, latency_ewma or consistent_hash (keyed on lb_hash_header)
+ALTER TABLE tenants ADD COLUMN lb_strategy VARCHAR(30) NOT NULL DEFAULT 'round_robin';
+ALTER TABLE tenants ADD COLUMN lb_hash_header VARCHAR(100);
+
+-- Backend that served the request, NULL when it never reached one
+ALTER TABLE access_logs ADD COLUMN backend VARCHAR(500);
@@ -0,0 +1,2 @@
+-- Path the gateway probes to check a pool backend's health, NULL for no active checks
+ALTER TABLE tenant_backends ADD COLUMN health_check_path VARCHAR(255);
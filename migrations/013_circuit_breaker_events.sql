@@ -0,0 +1,12 @@
+-- Circuit breaker state changes, one row per transition of a backend's breaker
+CREATE TABLE circuit_breaker_events (
+    id BIGSERIAL PRIMARY KEY,
+    backend VARCHAR(500) NOT NULL,
+    from_state VARCHAR(20) NOT NULL,
+    to_state VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL,
+    instance VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_circuit_breaker_events_backend ON circuit_breaker_events(backend, created_at DESC);
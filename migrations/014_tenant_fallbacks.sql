@@ -0,0 +1,18 @@
+-- Ordered fallback chain per tenant: when its backend answers an LLM request
+-- with 429 or 5xx, the steps are tried in position order
+CREATE TABLE tenant_fallbacks (
+    id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    url VARCHAR(500) NOT NULL,
+    model VARCHAR(100),                -- replaces the request's model, NULL keeps it
+    timeout_ms INTEGER NOT NULL DEFAULT 30000 CHECK (timeout_ms > 0),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, position)
+);
+
+-- Provider that answered, and which fallback step it was (NULL for the primary backend)
+ALTER TABLE access_logs ADD COLUMN provider VARCHAR(50);
+ALTER TABLE access_logs ADD COLUMN fallback_step INTEGER;
@@ -0,0 +1,3 @@
+-- API format each backend speaks; the gateway translates from and to OpenAI's
+ALTER TABLE tenants ADD COLUMN api_format VARCHAR(20) NOT NULL DEFAULT 'openai';
+ALTER TABLE tenant_fallbacks ADD COLUMN api_format VARCHAR(20) NOT NULL DEFAULT 'openai';
@@ -0,0 +1,19 @@
+-- Provider credentials the gateway adds to a tenant's upstream requests.
+-- Each secret is sealed with a data key of its own, stored wrapped by the
+-- master key; secret_hint keeps the last characters for display.
+CREATE TABLE tenant_credentials (
+    id SERIAL PRIMARY KEY,
+    tenant_id INTEGER REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    backend_url VARCHAR(500), -- the one backend, fallback or route URL it's sent to
+    location VARCHAR(10) NOT NULL DEFAULT 'header', -- header or query
+    param VARCHAR(100) NOT NULL,
+    prefix VARCHAR(50) NOT NULL DEFAULT '',
+    wrapped_key BYTEA NOT NULL,
+    sealed_secret BYTEA NOT NULL,
+    secret_hint VARCHAR(10) NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    rotated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(tenant_id, name)
+);
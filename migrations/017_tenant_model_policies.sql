@@ -0,0 +1,11 @@
+-- Which models a tenant's LLM requests may ask for, and where they go.
+-- Patterns are model names, or prefixes ending in *; an empty allowlist
+-- allows every model that isn't denied.
+CREATE TABLE tenant_model_policies (
+    tenant_id INTEGER PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    allowed_models TEXT[] NOT NULL DEFAULT '{}',
+    denied_models TEXT[] NOT NULL DEFAULT '{}',
+    aliases JSONB NOT NULL DEFAULT '{}',  -- alias -> model sent upstream
+    routes JSONB NOT NULL DEFAULT '[]',   -- [{"model", "url", "api_format"}], first match wins
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+-- The model a cached response came from. It is part of the prompt hash and
+-- of the embedding key, so one model's answers are never served for another.
+ALTER TABLE semantic_cache ADD COLUMN model VARCHAR(255) NOT NULL DEFAULT '';